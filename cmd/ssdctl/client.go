@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"ssd-assignment-api/models"
+	"strings"
+	"time"
+)
+
+// client talks to the configuration API.
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func newClient(server, token string) *client {
+	return &client{server: strings.TrimSuffix(server, "/"), token: token, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// fetch loads the published configurations and specific configs, the
+// drafts and the pending change requests.
+func (c *client) fetch() (documents, error) {
+	docs := documents{configs: map[string]models.Config{}, specifics: map[string]models.SpecificConfig{}, drafts: map[string]models.Config{}}
+
+	var configs []models.Config
+	if err := c.do(http.MethodGet, "/api/configuration/all", nil, &configs); err != nil {
+		return docs, err
+	}
+	for _, config := range configs {
+		docs.configs[config.ID] = config
+	}
+
+	var drafts []models.ConfigDraft
+	if err := c.do(http.MethodGet, "/api/configuration/drafts", nil, &drafts); err != nil {
+		return docs, err
+	}
+	for _, draft := range drafts {
+		docs.drafts[draft.Config.ID] = draft.Config
+	}
+
+	var specifics []models.SpecificConfig
+	if err := c.do(http.MethodGet, "/api/specific/all", nil, &specifics); err != nil {
+		return docs, err
+	}
+	for _, config := range specifics {
+		docs.specifics[config.ID] = config
+	}
+
+	if err := c.do(http.MethodGet, "/api/changes", nil, &docs.changes); err != nil {
+		return docs, err
+	}
+	return docs, nil
+}
+
+// apply sends one step through the resource endpoints and describes what
+// the server did with it: configurations are saved as drafts, deletes and
+// specific config changes become change requests. A configuration that only
+// exists as a draft is created by updating that draft.
+func (c *client) apply(s step, remote documents) (string, error) {
+	base := "/api/configuration/"
+	var body interface{} = s.config
+	if s.kind == kindSpecific {
+		base = "/api/specific/"
+		body = s.specific
+	}
+
+	method, path := http.MethodPut, base+url.PathEscape(s.id)
+	switch s.action {
+	case "create":
+		if _, draft := remote.drafts[s.id]; s.kind == kindSpecific || !draft {
+			method, path = http.MethodPost, base
+		}
+	case "delete":
+		method, body = http.MethodDelete, nil
+	}
+
+	var response struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+		State  string `json:"state"`
+	}
+	if err := c.do(method, path, body, &response); err != nil {
+		return "", err
+	}
+	switch {
+	case response.Status != "":
+		return fmt.Sprintf("change request %s is %s", response.ID, response.Status), nil
+	case response.State != "":
+		return "saved as " + response.State + ", publish it to serve it", nil
+	}
+	return "done", nil
+}
+
+func (c *client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr models.ErrorResponse
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s %s: %s", method, path, apiErr.Error)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
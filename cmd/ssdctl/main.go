@@ -0,0 +1,138 @@
+// Command ssdctl syncs a directory of configuration YAML with a running
+// server and repairs configuration directories.
+//
+//	ssdctl apply -f configs/ [--prune] [--check]
+//	ssdctl repair [-configs config_files] [-specific specific_configs] [--dry-run]
+//
+// apply classifies files by content: documents with a datasource, rules,
+// fallback or exclude are specific configs, the others configurations. The
+// server URL and token are read from --server/--token or SSD_SERVER/SSD_TOKEN.
+//
+// repair renames single-document files whose name does not match their ID,
+// keeping their extension.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"ssd-assignment-api/services"
+)
+
+const usage = `usage:
+  ssdctl apply -f <dir> [--prune] [--check] [--server url] [--token token]
+  ssdctl repair [-configs dir] [-specific dir] [--dry-run]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "apply":
+		runApply(os.Args[2:])
+	case "repair":
+		runRepair(os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runApply(args []string) {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	dir := flags.String("f", "", "directory of configuration YAML files")
+	prune := flags.Bool("prune", false, "delete configurations that are on the server but not in the directory")
+	check := flags.Bool("check", false, "only show the plan and exit with status 1 if there is drift")
+	server := flags.String("server", envOr("SSD_SERVER", "http://localhost:8000"), "server URL")
+	token := flags.String("token", os.Getenv("SSD_TOKEN"), "bearer token")
+	flags.Parse(args)
+
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "ssdctl: -f is required")
+		os.Exit(2)
+	}
+
+	code, err := apply(*dir, newClient(*server, *token), *prune, *check)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ssdctl:", err)
+		os.Exit(2)
+	}
+	os.Exit(code)
+}
+
+func runRepair(args []string) {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	configDir := flags.String("configs", "config_files", "configuration directory, empty to skip")
+	specificDir := flags.String("specific", "specific_configs", "specific configuration directory, empty to skip")
+	dryRun := flags.Bool("dry-run", false, "only list the files that would be renamed")
+	flags.Parse(args)
+
+	code := 0
+	for _, dir := range []string{*configDir, *specificDir} {
+		if dir == "" {
+			continue
+		}
+		renames, err := services.RepairFileNames(dir, !*dryRun)
+		for _, rename := range renames {
+			switch {
+			case rename.Skipped != "":
+				fmt.Printf("skipped %s: %s\n", rename.From, rename.Skipped)
+				code = 1
+			case *dryRun:
+				fmt.Printf("would rename %s to %s\n", rename.From, rename.To)
+			default:
+				fmt.Printf("renamed %s to %s\n", rename.From, rename.To)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ssdctl:", err)
+			os.Exit(2)
+		}
+	}
+	os.Exit(code)
+}
+
+// apply prints the plan and, unless check is set, applies it. It returns the
+// exit code: 1 when check finds drift, 0 otherwise. Steps already pending on
+// the server are not drift, as applying again would not send them.
+func apply(dir string, c *client, prune, check bool) (int, error) {
+	local, err := loadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	remote, err := c.fetch()
+	if err != nil {
+		return 0, err
+	}
+
+	plan := buildPlan(local, remote, prune)
+	printPlan(os.Stdout, plan)
+	if check {
+		for _, step := range plan {
+			if step.pending == "" {
+				return 1, nil
+			}
+		}
+		return 0, nil
+	}
+
+	for _, step := range plan {
+		if step.pending != "" {
+			continue
+		}
+		result, err := c.apply(step, remote)
+		if err != nil {
+			return 0, fmt.Errorf("%s %s %s: %w", step.action, step.kind, step.id, err)
+		}
+		fmt.Printf("%s %s %s: %s\n", step.action, step.kind, step.id, result)
+	}
+	return 0, nil
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
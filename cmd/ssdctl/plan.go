@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document kinds, named like the server's change request kinds.
+const (
+	kindConfig   = models.ChangeKindConfig
+	kindSpecific = models.ChangeKindSpecific
+)
+
+// documents holds configurations and specific configs by ID. drafts holds
+// the unpublished drafts on the server and changes its pending change
+// requests.
+type documents struct {
+	configs   map[string]models.Config
+	specifics map[string]models.SpecificConfig
+	drafts    map[string]models.Config
+	changes   []models.ChangeRequest
+}
+
+type step struct {
+	action   string // create, update or delete
+	kind     string
+	id       string
+	config   models.Config
+	specific models.SpecificConfig
+	pending  string // what already holds the change on the server, if anything
+}
+
+// loadDir reads every document of the .yaml, .yml and .json files below
+// dir, in the flat or the resource form.
+func loadDir(dir string) (documents, error) {
+	docs := documents{configs: map[string]models.Config{}, specifics: map[string]models.SpecificConfig{}}
+	seen := map[string]string{}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for index := 1; ; index++ {
+			var node yaml.Node
+			if err := decoder.Decode(&node); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			name := path
+			if index > 1 {
+				name = fmt.Sprintf("%s (document %d)", path, index)
+			}
+			if err := docs.add(node, seen, name); err != nil {
+				return err
+			}
+		}
+	})
+	return docs, err
+}
+
+// add classifies a document by its keys and stores it. Empty documents are
+// skipped.
+func (docs documents) add(node yaml.Node, seen map[string]string, name string) error {
+	var keys map[string]interface{}
+	if err := node.Decode(&keys); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if keys == nil {
+		return nil
+	}
+
+	// Resources name their kind; flat documents are told apart by their keys
+	kind := kindConfig
+	_, resource := keys["apiVersion"]
+	if resource && keys["kind"] == models.KindSpecificConfig {
+		kind = kindSpecific
+	}
+	for _, key := range []string{"datasource", "rules", "fallback", "exclude"} {
+		if _, ok := keys[key]; ok && !resource {
+			kind = kindSpecific
+		}
+	}
+
+	var id string
+	if kind == kindSpecific {
+		config, err := decodeSpecific(node, resource)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		id = config.ID
+		docs.specifics[id] = config
+	} else {
+		config, err := decodeConfig(node, resource)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		id = config.ID
+		docs.configs[id] = config
+	}
+
+	if id == "" {
+		return fmt.Errorf("%s: id is required", name)
+	}
+	if other, ok := seen[kind+":"+id]; ok {
+		return fmt.Errorf("%s: %s '%s' is also defined in %s", name, kind, id, other)
+	}
+	seen[kind+":"+id] = name
+	return nil
+}
+
+func decodeConfig(node yaml.Node, resource bool) (models.Config, error) {
+	var config models.Config
+	if !resource {
+		err := node.Decode(&config)
+		return config, err
+	}
+	var r models.ConfigResource
+	if err := node.Decode(&r); err != nil {
+		return config, err
+	}
+	return r.Config()
+}
+
+func decodeSpecific(node yaml.Node, resource bool) (models.SpecificConfig, error) {
+	var config models.SpecificConfig
+	if !resource {
+		err := node.Decode(&config)
+		return config, err
+	}
+	var r models.SpecificConfigResource
+	if err := node.Decode(&r); err != nil {
+		return config, err
+	}
+	return r.SpecificConfig()
+}
+
+// buildPlan compares the directory with the server. Deletes are only
+// planned with prune. Steps the server already holds as a pending change
+// request or draft are marked so applying again does not repeat them.
+func buildPlan(local, remote documents, prune bool) []step {
+	var plan []step
+	for _, id := range sortedIDs(local.configs) {
+		config := local.configs[id]
+		current, exists := remote.configs[id]
+		switch {
+		case !exists:
+			plan = append(plan, step{action: "create", kind: kindConfig, id: id, config: config})
+		case configYAML(current) != configYAML(config):
+			plan = append(plan, step{action: "update", kind: kindConfig, id: id, config: config})
+		}
+	}
+	for _, id := range sortedIDs(local.specifics) {
+		config := local.specifics[id]
+		current, exists := remote.specifics[id]
+		switch {
+		case !exists:
+			plan = append(plan, step{action: "create", kind: kindSpecific, id: id, specific: config})
+		case specificYAML(current) != specificYAML(config):
+			plan = append(plan, step{action: "update", kind: kindSpecific, id: id, specific: config})
+		}
+	}
+
+	if prune {
+		for _, id := range sortedIDs(remote.configs) {
+			if _, ok := local.configs[id]; !ok {
+				plan = append(plan, step{action: "delete", kind: kindConfig, id: id})
+			}
+		}
+		for _, id := range sortedIDs(remote.specifics) {
+			if _, ok := local.specifics[id]; !ok {
+				plan = append(plan, step{action: "delete", kind: kindSpecific, id: id})
+			}
+		}
+	}
+	for i := range plan {
+		plan[i].pending = remote.pendingFor(plan[i])
+	}
+	return plan
+}
+
+// pendingFor returns what already holds a step on the server: a pending
+// change request making the same change or, for a configuration, a draft
+// with the same content. It is empty when the step still has to be sent.
+func (docs documents) pendingFor(s step) string {
+	for _, request := range docs.changes {
+		if request.Kind != s.kind || request.Operation != s.action || request.TargetID != s.id {
+			continue
+		}
+		switch {
+		case s.action == "delete",
+			s.kind == kindSpecific && request.SpecificConfig != nil && specificYAML(*request.SpecificConfig) == specificYAML(s.specific):
+			return "change request " + request.ID
+		}
+	}
+	if draft, ok := docs.drafts[s.id]; ok && s.kind == kindConfig && s.action != "delete" && configYAML(draft) == configYAML(s.config) {
+		return "draft"
+	}
+	return ""
+}
+
+func printPlan(w io.Writer, plan []step) {
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "No changes. The server matches the directory.")
+		return
+	}
+
+	counts := map[string]int{}
+	fmt.Fprintln(w, "Plan:")
+	for _, step := range plan {
+		if step.pending != "" {
+			fmt.Fprintf(w, "  = %s %s (%s pending as %s)\n", step.kind, step.id, step.action, step.pending)
+			counts["pending"]++
+			continue
+		}
+		symbol := map[string]string{"create": "+", "update": "~", "delete": "-"}[step.action]
+		fmt.Fprintf(w, "  %s %s %s\n", symbol, step.kind, step.id)
+		counts[step.action]++
+	}
+	fmt.Fprintf(w, "%d to create, %d to update, %d to delete, %d already pending.\n",
+		counts["create"], counts["update"], counts["delete"], counts["pending"])
+}
+
+// configYAML and specificYAML render the content of a config for comparison;
+// the timestamps the server maintains are left out.
+func configYAML(config models.Config) string {
+	config = config.WithoutTimestamps()
+	return documentYAML(&config)
+}
+
+func specificYAML(config models.SpecificConfig) string {
+	config = config.WithoutTimestamps()
+	return documentYAML(&config)
+}
+
+// documentYAML renders both kinds with the same encoder, so equal content
+// always compares equal.
+func documentYAML(value interface{}) string {
+	data, _ := yaml.Marshal(value)
+	return strings.TrimSpace(string(data))
+}
+
+func sortedIDs[T any](m map[string]T) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
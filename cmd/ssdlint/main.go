@@ -0,0 +1,51 @@
+// Command ssdlint checks configuration directories without a running server.
+//
+//	ssdlint [-configs config_files] [-specific specific_configs] [-experiments dir] [-format text|json|github|junit]
+//
+// Files are parsed the same way the server loads them and every validation
+// is run: action schema, selectors, the HTML policy, patterns, references
+// between files, duplicate IDs and file names that do not match their ID.
+// The exit status is 1 when an error was found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"ssd-assignment-api/services"
+)
+
+func main() {
+	configDir := flag.String("configs", "config_files", "configuration directory, empty to skip")
+	specificDir := flag.String("specific", "specific_configs", "specific configuration directory, empty to skip")
+	experimentDir := flag.String("experiments", "", "experiment directory; experiment references are only checked when it is set")
+	format := flag.String("format", "text", "output format: text, json, github or junit")
+	stripWWW := flag.Bool("strip-www", os.Getenv("MATCH_STRIP_WWW") == "true", "normalize host patterns like MATCH_STRIP_WWW")
+	stripPort := flag.Bool("strip-port", os.Getenv("MATCH_STRIP_PORT") == "true", "normalize host patterns like MATCH_STRIP_PORT")
+	flag.Parse()
+
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ssdlint: unknown format '%s'\n", *format)
+		os.Exit(2)
+	}
+
+	report, err := services.Lint(services.LintOptions{
+		ConfigDir:     *configDir,
+		SpecificDir:   *specificDir,
+		ExperimentDir: *experimentDir,
+		Normalize:     services.NormalizeOptions{StripWWW: *stripWWW, StripPort: *stripPort},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ssdlint:", err)
+		os.Exit(2)
+	}
+
+	if err := write(os.Stdout, report); err != nil {
+		fmt.Fprintln(os.Stderr, "ssdlint:", err)
+		os.Exit(2)
+	}
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"ssd-assignment-api/models"
+	"strings"
+)
+
+var writers = map[string]func(io.Writer, models.LintReport) error{
+	"text":   writeText,
+	"json":   writeJSON,
+	"github": writeGitHub,
+	"junit":  writeJUnit,
+}
+
+// location formats file:line:column, leaving out what is unknown.
+func location(issue models.LintIssue) string {
+	switch {
+	case issue.Line == 0:
+		return issue.File
+	case issue.Column == 0:
+		return fmt.Sprintf("%s:%d", issue.File, issue.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", issue.File, issue.Line, issue.Column)
+}
+
+func writeText(w io.Writer, report models.LintReport) error {
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, "%s: %s: %s (%s)\n", location(issue), issue.Severity, issue.Message, issue.Rule)
+	}
+	_, err := fmt.Fprintf(w, "%d files checked, %d errors, %d warnings\n",
+		len(report.Files), report.Errors, len(report.Issues)-report.Errors)
+	return err
+}
+
+func writeJSON(w io.Writer, report models.LintReport) error {
+	if report.Issues == nil {
+		report.Issues = []models.LintIssue{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// writeGitHub writes GitHub Actions workflow commands, which show up as
+// annotations on the changed lines.
+func writeGitHub(w io.Writer, report models.LintReport) error {
+	escapeData := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	escapeProperty := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+	for _, issue := range report.Issues {
+		properties := "file=" + escapeProperty.Replace(issue.File)
+		if issue.Line > 0 {
+			properties += fmt.Sprintf(",line=%d", issue.Line)
+		}
+		if issue.Column > 0 {
+			properties += fmt.Sprintf(",col=%d", issue.Column)
+		}
+		properties += ",title=" + escapeProperty.Replace("ssdlint "+issue.Rule)
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", issue.Severity, properties, escapeData.Replace(issue.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a test case per file with a failure per error; warnings
+// are listed in the case's output.
+func writeJUnit(w io.Writer, report models.LintReport) error {
+	suite := junitSuite{Name: "ssdlint", Tests: len(report.Files)}
+	cases := make(map[string]*junitCase, len(report.Files))
+	for _, file := range report.Files {
+		suite.Cases = append(suite.Cases, junitCase{Name: file, ClassName: "ssdlint"})
+	}
+	for i := range suite.Cases {
+		cases[suite.Cases[i].Name] = &suite.Cases[i]
+	}
+
+	for _, issue := range report.Issues {
+		testCase := cases[issue.File]
+		if testCase == nil {
+			continue
+		}
+		line := fmt.Sprintf("%s: %s (%s)", location(issue), issue.Message, issue.Rule)
+		if issue.Severity != models.SeverityError {
+			testCase.SystemOut += line + "\n"
+			continue
+		}
+		if len(testCase.Failures) == 0 {
+			suite.Failures++
+		}
+		testCase.Failures = append(testCase.Failures, junitFailure{Message: issue.Message, Type: issue.Rule, Text: line})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -15,131 +15,101 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/configuration": {
-            "post": {
-                "description": "Adds a new configuration to the system",
-                "consumes": [
-                    "application/json"
+        "/api/admin/load-report": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Lists how many files every environment loaded at startup and, in lenient load mode, every file that\nwas skipped with the reason",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "configuration"
-                ],
-                "summary": "Add a new configuration",
-                "parameters": [
-                    {
-                        "description": "Configuration",
-                        "name": "config",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Config"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Report the startup load",
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/models.Config"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.LoadReport"
                         }
                     }
                 }
             }
         },
-        "/api/configuration/all": {
+        "/api/audit": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves all configurations stored in memory",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists audited changes such as kill switch toggles and rollout changes, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "configuration"
+                    "audit"
                 ],
-                "summary": "Get all configurations",
+                "summary": "Get the audit log",
                 "responses": {
                     "200": {
-                        "description": "List of configurations",
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Config"
+                                "$ref": "#/definitions/models.AuditEntry"
                             }
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/configuration/{id}": {
+        "/api/changes": {
             "get": {
-                "description": "Retrieves a specific configuration by its ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists change requests with the given status, pending ones by default; status=all lists every request",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "configuration"
+                    "changes"
                 ],
-                "summary": "Get configuration by ID",
+                "summary": "List change requests",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Configuration ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "pending, approved, rejected or all",
+                        "name": "status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Config"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ChangeRequest"
+                            }
                         }
                     }
                 }
             },
-            "put": {
-                "description": "Updates an existing configuration by ID",
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes deleting or archiving a configuration (kind config), creating, updating or deleting a\nspecific configuration (kind specific) or an experiment (kind experiment), or a rollout, import or\npromotion. Anything but a specific config change or a config delete needs the publisher role. The\nchange is applied once a different user with the approver role approves it",
                 "consumes": [
                     "application/json"
                 ],
@@ -147,32 +117,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "configuration"
+                    "changes"
                 ],
-                "summary": "Update an existing configuration",
+                "summary": "Propose a change",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Configuration ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated configuration",
-                        "name": "config",
+                        "description": "Proposed change",
+                        "name": "proposal",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Config"
+                            "$ref": "#/definitions/models.ChangeProposal"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/models.Config"
+                            "$ref": "#/definitions/models.ChangeRequest"
                         }
                     },
                     "400": {
@@ -180,51 +143,100 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    }
+                }
+            }
+        },
+        "/api/changes/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "changes"
+                ],
+                "summary": "Get a change request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Change request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.ChangeRequest"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes a specific configuration by ID",
+            }
+        },
+        "/api/changes/{id}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies a pending change. Requires the approver role; the proposer can not approve their own change",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "configuration"
+                    "changes"
                 ],
-                "summary": "Delete a configuration",
+                "summary": "Approve a change request",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Configuration ID",
+                        "description": "Change request ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Review comment",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeReview"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.MessageResponse"
+                            "$ref": "#/definitions/models.ChangeRequest"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -232,65 +244,72 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/specific": {
-            "get": {
-                "description": "Get configuration IDs based on host, url or page",
+        "/api/changes/{id}/reject": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Closes a pending change without applying it. Requires the approver role; the proposer can not reject their own change",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "specific"
+                    "changes"
                 ],
-                "summary": "Get matching configurations",
+                "summary": "Reject a change request",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Target host",
-                        "name": "host",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Target URL path",
-                        "name": "url",
-                        "in": "query"
+                        "description": "Change request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Target page name",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Review comment",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeReview"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "array",
-                                "items": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/models.ChangeRequest"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/configuration": {
             "post": {
-                "description": "Add a new specific configuration mapping",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Saves a new configuration as a draft. It is served once it has been submitted and published.\nThe body may be in the flat or the resource form",
                 "consumes": [
                     "application/json"
                 ],
@@ -298,17 +317,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "specific"
+                    "configuration"
                 ],
-                "summary": "Add new specific configuration",
+                "summary": "Add a new configuration",
                 "parameters": [
                     {
-                        "description": "Specific Configuration",
+                        "description": "Configuration",
                         "name": "config",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.SpecificConfig"
+                            "$ref": "#/definitions/models.Config"
                         }
                     }
                 ],
@@ -316,7 +335,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.SpecificConfig"
+                            "$ref": "#/definitions/models.ConfigDraft"
                         }
                     },
                     "400": {
@@ -334,28 +353,50 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/specific/all": {
+        "/api/configuration/all": {
             "get": {
-                "description": "Retrieves all specific configurations",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the configurations ordered by ID, optionally filtered by a label selector, in the flat\nform or, with format=resource, as apiVersion/kind/metadata/spec resources",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "specific"
+                    "configuration"
+                ],
+                "summary": "Get all configurations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Response form: flat (default) or resource",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label selector, e.g. team=growth,brand in (x,y),!deprecated",
+                        "name": "labelSelector",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all specific configurations",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "List of configurations",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.SpecificConfig"
+                                "$ref": "#/definitions/models.Config"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -363,16 +404,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/specific/{id}": {
+        "/api/configuration/drafts": {
             "get": {
-                "description": "Retrieves a specific configuration by its ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the drafts and the drafts in review of every configuration",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "specific"
+                    "workflow"
                 ],
-                "summary": "Get specific configuration by ID",
+                "summary": "Get all configuration drafts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ConfigDraft"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/configuration/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a specific configuration by its ID, in the flat form or, with format=resource,\nas an apiVersion/kind/metadata/spec resource",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configuration"
+                ],
+                "summary": "Get configuration by ID",
                 "parameters": [
                     {
                         "type": "string",
@@ -380,13 +454,25 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response form: flat (default) or resource",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SpecificConfig"
+                            "$ref": "#/definitions/models.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "404": {
@@ -394,11 +480,22 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             },
             "put": {
-                "description": "Updates an existing specific configuration",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Saves the changes as the draft of the configuration; the published revision keeps being served\nuntil the draft is published. The body may be in the flat or the resource form",
                 "consumes": [
                     "application/json"
                 ],
@@ -406,9 +503,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "specific"
+                    "configuration"
                 ],
-                "summary": "Update specific configuration",
+                "summary": "Update an existing configuration",
                 "parameters": [
                     {
                         "type": "string",
@@ -418,12 +515,12 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Updated Configuration",
+                        "description": "Updated configuration",
                         "name": "config",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.SpecificConfig"
+                            "$ref": "#/definitions/models.Config"
                         }
                     }
                 ],
@@ -431,7 +528,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SpecificConfig"
+                            "$ref": "#/definitions/models.ConfigDraft"
                         }
                     },
                     "400": {
@@ -439,15 +536,32 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             },
             "delete": {
-                "description": "Deletes a specific configuration by ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes deleting a configuration by ID. It is deleted once another user approves the change request",
                 "tags": [
-                    "specific"
+                    "configuration"
                 ],
-                "summary": "Delete specific configuration",
+                "summary": "Delete a configuration",
                 "parameters": [
                     {
                         "type": "string",
@@ -458,10 +572,10 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/models.MessageResponse"
+                            "$ref": "#/definitions/models.ChangeRequest"
                         }
                     },
                     "404": {
@@ -469,161 +583,2312 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/auth/login": {
+        "/api/configuration/{id}/archive": {
             "post": {
-                "description": "This endpoint allows an existing user to log in using their username and password",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes to stop serving the configuration but keep it so it can be edited and published again.\nIt is archived once another user approves the change request. Requires the publisher role",
+                "tags": [
+                    "workflow"
+                ],
+                "summary": "Archive a published configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/configuration/{id}/diff": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Returns a line diff of the YAML of the published revision and the draft",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "workflow"
                 ],
-                "summary": "Log in an existing user",
+                "summary": "Diff a draft against the published configuration",
                 "parameters": [
                     {
-                        "description": "User login info",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Login successful",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ConfigDiff"
                         }
                     },
-                    "400": {
-                        "description": "Bad request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "This endpoint registers a new user with username and password",
-                "consumes": [
-                    "application/json"
+        "/api/configuration/{id}/draft": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "workflow"
                 ],
-                "summary": "Register a new user",
+                "summary": "Get the draft of a configuration",
                 "parameters": [
                     {
-                        "description": "User info",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.ConfigDraft"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "tags": [
+                    "workflow"
+                ],
+                "summary": "Discard the draft of a configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User registered successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.MessageResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Action": {
-            "type": "object",
-            "properties": {
-                "newElement": {
-                    "description": "New HTML element (for replace)",
-                    "type": "string"
-                },
-                "newValue": {
-                    "description": "New value (for alter)",
-                    "type": "string"
-                },
-                "oldValue": {
-                    "description": "Old value (for alter)",
-                    "type": "string"
-                },
-                "position": {
-                    "description": "Position (for insert: before/after)",
-                    "type": "string"
-                },
-                "selector": {
-                    "description": "CSS selector (for remove/replace)",
-                    "type": "string"
-                },
-                "target": {
-                    "description": "Target element (for insert)",
-                    "type": "string"
+        },
+        "/api/configuration/{id}/publish": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Makes a draft in review the served configuration. Requires the approver role; the user who\nedited or submitted the draft can not publish it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflow"
+                ],
+                "summary": "Publish a reviewed draft",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Config"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/configuration/{id}/rollout": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes serving the configuration to the given percentage of visitors, bucketed by visitor ID.\nIt is applied once another user approves the change request. Rolling back to 0 stops serving\nthe configuration at once and answers 200 with it; the change is recorded in the audit log.\nRequires the publisher role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "configuration"
+                ],
+                "summary": "Set the rollout percentage of a configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rollout percentage (0-100)",
+                        "name": "rollout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RolloutUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Config"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/configuration/{id}/status": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns draft, in_review, published or archived",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflow"
+                ],
+                "summary": "Get the workflow state of a configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConfigStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/configuration/{id}/submit": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflow"
+                ],
+                "summary": "Submit a draft for review",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConfigDraft"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/drift": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists every config and specific config that is missing from an environment or differs between\nenvironments, with a checksum of each revision. Environments are read again from disk",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environment"
+                ],
+                "summary": "Report drift between environments",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DriftReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/environments": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the environments in promotion order and marks the one this server serves",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environment"
+                ],
+                "summary": "List environments",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.EnvironmentSummary"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/experiment": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes an A/B experiment. Mappings reference it as \"experiment:\u003cid\u003e\" and visitors are bucketed\ninto its weighted variants by hashing their visitor ID with the salt. It is added once another\nuser approves the change request. Requires the publisher role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "experiment"
+                ],
+                "summary": "Add new experiment",
+                "parameters": [
+                    {
+                        "description": "Experiment",
+                        "name": "experiment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Experiment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/experiment/all": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all A/B experiments",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "experiment"
+                ],
+                "summary": "Get all experiments",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Experiment"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/experiment/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves an A/B experiment by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "experiment"
+                ],
+                "summary": "Get experiment by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Experiment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Experiment"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes a change to an A/B experiment. Changing the salt, holdout or weights reassigns visitors.\nIt is applied once another user approves the change request. Requires the publisher role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "experiment"
+                ],
+                "summary": "Update experiment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Experiment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated Experiment",
+                        "name": "experiment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Experiment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes deleting an A/B experiment by ID. It is deleted once another user approves the change\nrequest. Requires the publisher role",
+                "tags": [
+                    "experiment"
+                ],
+                "summary": "Delete experiment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Experiment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams every configuration and specific configuration as YAML in a tar.gz or zip archive,\ntogether with a manifest.yaml listing the SHA-256 checksum of every file",
+                "produces": [
+                    "application/gzip",
+                    "application/zip"
+                ],
+                "tags": [
+                    "archive"
+                ],
+                "summary": "Export all configurations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "tar.gz (default) or zip",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Imports a tar.gz or zip archive produced by GET /api/export. Every file is checked against the\nmanifest and validated before anything is changed. With dry_run the result is returned at once;\notherwise the import is proposed as a change request and applied all or nothing once another user\napproves it. Requires the publisher role",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "archive"
+                ],
+                "summary": "Import configurations from an archive",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Archive",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "What to do with existing IDs: fail (default), skip or overwrite",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only report what would be created, updated and skipped",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dry run",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportResult"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/killswitch": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the global, per-host and per-config kill switches that are on",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "killswitch"
+                ],
+                "summary": "Get kill switches",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.KillSwitches"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turns the global, a per-host or a per-config kill switch on or off. Killed configs stay stored\nbut are no longer served. The switch changes at once and every change is recorded in the audit\nlog. Requires the publisher role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "killswitch"
+                ],
+                "summary": "Toggle a kill switch",
+                "parameters": [
+                    {
+                        "description": "Scope (global, host or config), target and new state",
+                        "name": "toggle",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.KillSwitchToggle"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.KillSwitches"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/promote": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Copies the published revisions of the selected configs and specific configs from one environment\nto the next. With dry_run the planned creates and updates are returned with a YAML diff and nothing\nis written; otherwise the promotion is proposed as a change request and applied once another user\napproves it. Requires the publisher role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environment"
+                ],
+                "summary": "Promote configurations to the next environment",
+                "parameters": [
+                    {
+                        "description": "Source environment and selected IDs",
+                        "name": "promotion",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PromoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dry run",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromoteResult"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/schedule": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the upcoming activations and deactivations of configurations and specific config mappings, earliest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "List upcoming schedule transitions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ScheduleTransition"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/specific": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get configuration IDs based on host, url or page. Datasource keys may be exact values,\nhost wildcards (*.example.com), url globs (/products/*, /docs/**), named url parameters\n(/products/:id) or anchored regexes prefixed with \"re:\". More specific keys rank first on equal score.\nHosts and paths are normalized (case, punycode, default ports, duplicate and trailing slashes,\npercent-encoding) before matching, and so are the datasource keys. When nothing matches, the\nper-host or global fallback IDs are returned with fallback=true. Experiment references are replaced\nby the IDs of the visitor's variant, which is reported under experiments.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Get matching configurations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full visitor URL; fills host, url and query when they are not given",
+                        "name": "href",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target URL path",
+                        "name": "url",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target page name",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Raw query string of the visitor's request, used by rule conditions",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Visitor user agent, defaults to the User-Agent header",
+                        "name": "ua",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device class (mobile, tablet, desktop, tv, bot), defaults to the one derived from the user agent",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Visitor languages, defaults to the Accept-Language header",
+                        "name": "lang",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Visitor ID experiments bucket on, defaults to the X-Visitor-ID header",
+                        "name": "visitor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching IDs, the fallback IDs, or an empty list with a reason",
+                        "schema": {
+                            "$ref": "#/definitions/models.MatchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes a new specific configuration mapping. It is added once another user approves the change request.\nThe body may be in the flat or the resource form",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Add new specific configuration",
+                "parameters": [
+                    {
+                        "description": "Specific Configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SpecificConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/specific/all": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the specific configurations ordered by ID, optionally filtered by a label selector, in\nthe flat form or, with format=resource, as apiVersion/kind/metadata/spec resources",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Get all specific configurations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Response form: flat (default) or resource",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label selector, e.g. team=growth,brand in (x,y),!deprecated",
+                        "name": "labelSelector",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SpecificConfig"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/specific/explain": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Shows, for every candidate config ID, which specific config and rule (host, url or page pattern, or compound rule)\ncontributed how many points, and the final order including tie-breaks by specificity and ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Explain configuration matching",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full visitor URL; fills host, url and query when they are not given",
+                        "name": "href",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target URL path",
+                        "name": "url",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target page name",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Raw query string of the visitor's request, used by rule conditions",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Visitor user agent, defaults to the User-Agent header",
+                        "name": "ua",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device class (mobile, tablet, desktop, tv, bot), defaults to the one derived from the user agent",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Visitor languages, defaults to the Accept-Language header",
+                        "name": "lang",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Visitor ID experiments bucket on, defaults to the X-Visitor-ID header",
+                        "name": "visitor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MatchExplanation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/specific/resolve": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Matches the request like GET /api/specific and returns the matched configurations themselves,\nin ranking order, together with any exclusions that vetoed config IDs and the assigned experiment variants",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Resolve matching configurations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full visitor URL; fills host, url and query when they are not given",
+                        "name": "href",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target URL path",
+                        "name": "url",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target page name",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Raw query string of the visitor's request",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Visitor ID experiments bucket on, defaults to the X-Visitor-ID header",
+                        "name": "visitor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ResolveResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/specific/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a specific configuration by its ID, in the flat form or, with format=resource,\nas an apiVersion/kind/metadata/spec resource",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Get specific configuration by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response form: flat (default) or resource",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SpecificConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes an update of a specific configuration. It is applied once another user approves the change request.\nThe body may be in the flat or the resource form",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Update specific configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated Configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SpecificConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proposes deleting a specific configuration by ID. It is deleted once another user approves the change request",
+                "tags": [
+                    "specific"
+                ],
+                "summary": "Delete specific configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Configuration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "This endpoint allows an existing user to log in using their username and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Log in an existing user",
+                "parameters": [
+                    {
+                        "description": "User login info",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Login successful",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "This endpoint registers a new user with username and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User info",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User registered successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ready": {
+            "get": {
+                "description": "Reports ready, or degraded when files of the served environment were skipped at startup. The\nserver keeps serving in both cases, so the status code is always 200",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReadinessStatus"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.Action": {
+            "type": "object",
+            "properties": {
+                "newElement": {
+                    "description": "New HTML element (for replace)",
+                    "type": "string"
+                },
+                "newValue": {
+                    "description": "New value (for alter)",
+                    "type": "string"
+                },
+                "oldValue": {
+                    "description": "Old value (for alter)",
+                    "type": "string"
+                },
+                "position": {
+                    "description": "Position (for insert: before/after)",
+                    "type": "string"
+                },
+                "selector": {
+                    "description": "CSS selector (for remove/replace)",
+                    "type": "string"
+                },
+                "target": {
+                    "description": "Target element (for insert)",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Action type (remove, replace, insert, alter)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "e.g. killswitch.on, config.rollout",
+                    "type": "string"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CandidateExplanation": {
+            "type": "object",
+            "properties": {
+                "broadness": {
+                    "type": "integer"
+                },
+                "config_id": {
+                    "type": "string"
+                },
+                "contributions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MatchContribution"
+                    }
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "rank": {
+                    "type": "integer"
+                },
+                "ranked_by": {
+                    "description": "priority, score, specificity or id, relative to the previous candidate",
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ChangeProposal": {
+            "type": "object",
+            "required": [
+                "kind",
+                "operation"
+            ],
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "experiment": {
+                    "$ref": "#/definitions/models.Experiment"
+                },
+                "import": {
+                    "$ref": "#/definitions/models.ImportProposal"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "operation": {
+                    "type": "string"
+                },
+                "promotion": {
+                    "$ref": "#/definitions/models.PromoteRequest"
+                },
+                "rollout": {
+                    "type": "integer"
+                },
+                "specific_config": {
+                    "$ref": "#/definitions/models.SpecificConfig"
+                },
+                "target_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChangeRequest": {
+            "type": "object",
+            "required": [
+                "kind",
+                "operation"
+            ],
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "experiment": {
+                    "$ref": "#/definitions/models.Experiment"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "import": {
+                    "$ref": "#/definitions/models.ImportProposal"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "operation": {
+                    "type": "string"
+                },
+                "promotion": {
+                    "$ref": "#/definitions/models.PromoteRequest"
+                },
+                "proposed_at": {
+                    "type": "string"
+                },
+                "proposed_by": {
+                    "type": "string"
+                },
+                "review_comment": {
+                    "type": "string"
+                },
+                "reviewed_at": {
+                    "type": "string"
+                },
+                "reviewed_by": {
+                    "type": "string"
+                },
+                "rollout": {
+                    "type": "integer"
+                },
+                "specific_config": {
+                    "$ref": "#/definitions/models.SpecificConfig"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChangeReview": {
+            "type": "object",
+            "required": [
+                "comment"
+            ],
+            "properties": {
+                "comment": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Condition": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Condition"
+                    }
+                },
+                "any": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Condition"
+                    }
+                },
+                "cookies": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "device": {
+                    "description": "mobile, tablet, desktop, tv or bot",
+                    "type": "string"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "host": {
+                    "type": "string"
+                },
+                "language": {
+                    "description": "accepted language, \"tr\" also matches \"tr-TR\"",
+                    "type": "string"
+                },
+                "not": {
+                    "$ref": "#/definitions/models.Condition"
+                },
+                "page": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                },
+                "userAgent": {
+                    "description": "pattern on the raw User-Agent",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Config": {
+            "type": "object",
+            "properties": {
+                "actions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Action"
+                    }
+                },
+                "activeFrom": {
+                    "type": "string"
+                },
+                "activeUntil": {
+                    "type": "string"
+                },
+                "annotations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "rollout": {
+                    "description": "Rollout is the percentage of visitors the config is served to, bucketed\nby visitor ID; it is served to everyone when unset.",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConfigDiff": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "type": "boolean"
+                },
+                "draft": {
+                    "$ref": "#/definitions/models.Config"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "published": {
+                    "$ref": "#/definitions/models.Config"
+                }
+            }
+        },
+        "models.ConfigDraft": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "$ref": "#/definitions/models.Config"
+                },
+                "state": {
+                    "description": "draft or in_review",
+                    "type": "string"
+                },
+                "submittedBy": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "updatedBy": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConfigStatus": {
+            "type": "object",
+            "properties": {
+                "has_draft": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "published": {
+                    "type": "boolean"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DataSource": {
+            "type": "object",
+            "properties": {
+                "hosts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.Mapping"
+                    }
+                },
+                "pages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.Mapping"
+                    }
+                },
+                "urls": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.Mapping"
+                    }
+                }
+            }
+        },
+        "models.DriftEntry": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "revisions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.DriftReport": {
+            "type": "object",
+            "properties": {
+                "drift": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DriftEntry"
+                    }
+                },
+                "environments": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "in_sync": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.EnvironmentSummary": {
+            "type": "object",
+            "properties": {
+                "configs": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "served": {
+                    "description": "the environment this server matches against",
+                    "type": "boolean"
+                },
+                "specific_configs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Exclude": {
+            "type": "object",
+            "properties": {
+                "hosts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "pages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "urls": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "models.Experiment": {
+            "type": "object",
+            "properties": {
+                "holdout": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "salt": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Variant"
+                    }
+                }
+            }
+        },
+        "models.ExperimentAssignment": {
+            "type": "object",
+            "properties": {
+                "config_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "experiment": {
+                    "type": "string"
+                },
+                "holdout": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "variant": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Fallback": {
+            "type": "object",
+            "properties": {
+                "hosts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ImportProposal": {
+            "type": "object",
+            "properties": {
+                "configs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Config"
+                    }
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "specific_configs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SpecificConfig"
+                    }
+                }
+            }
+        },
+        "models.ImportResult": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.KillSwitchToggle": {
+            "type": "object",
+            "required": [
+                "scope"
+            ],
+            "properties": {
+                "killed": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.KillSwitches": {
+            "type": "object",
+            "properties": {
+                "configs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "global": {
+                    "type": "boolean"
+                },
+                "hosts": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.LoadDiagnostic": {
+            "type": "object",
+            "properties": {
+                "environment": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "file": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "config, draft, archived or specific",
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoadReport": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LoadDiagnostic"
+                    }
+                },
+                "loaded": {
+                    "description": "number of documents loaded",
+                    "type": "integer"
+                },
+                "loaded_at": {
+                    "type": "string"
+                },
+                "mode": {
+                    "description": "strict or lenient",
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LoadDiagnostic"
+                    }
+                }
+            }
+        },
+        "models.Mapping": {
+            "type": "object",
+            "properties": {
+                "activeFrom": {
+                    "type": "string"
+                },
+                "activeUntil": {
+                    "type": "string"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "query": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "stop": {
+                    "type": "boolean"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MatchContribution": {
+            "type": "object",
+            "properties": {
+                "broadness": {
+                    "type": "integer"
+                },
+                "config_ids": {
+                    "description": "only set for stopped mappings",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "pattern": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "query": {
+                    "description": "query parameters that satisfied the mapping",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "rule": {
+                    "description": "host, url, page or rule",
+                    "type": "string"
                 },
-                "type": {
-                    "description": "Action type (remove, replace, insert, alter)",
+                "specific_config": {
                     "type": "string"
                 }
             }
         },
-        "models.Config": {
+        "models.MatchExclusion": {
             "type": "object",
             "properties": {
-                "actions": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Action"
-                    }
+                "config_id": {
+                    "type": "string"
                 },
-                "id": {
+                "pattern": {
+                    "type": "string"
+                },
+                "rule": {
+                    "description": "host, url or page",
+                    "type": "string"
+                },
+                "specific_config": {
                     "type": "string"
                 }
             }
         },
-        "models.DataSource": {
+        "models.MatchExplanation": {
             "type": "object",
             "properties": {
-                "hosts": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        }
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CandidateExplanation"
                     }
                 },
-                "pages": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        }
+                "config_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
                 },
-                "urls": {
+                "device": {
+                    "type": "string"
+                },
+                "excluded": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MatchExclusion"
+                    }
+                },
+                "experiments": {
+                    "description": "Experiments lists the variant assigned for every matched experiment\nreference; ConfigIDs holds the variant IDs in its place.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExperimentAssignment"
+                    }
+                },
+                "fallback": {
+                    "type": "boolean"
+                },
+                "filtered": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MatchFiltered"
+                    }
+                },
+                "host": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "string"
+                },
+                "query": {
                     "type": "object",
                     "additionalProperties": {
                         "type": "array",
@@ -631,13 +2896,61 @@ const docTemplate = `{
                             "type": "string"
                         }
                     }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "stopped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MatchContribution"
+                    }
+                },
+                "stopped_below": {
+                    "description": "StoppedBelow is set when a matched mapping with stop discarded every\nmatched mapping of lower priority; those are listed in Stopped.",
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "models.ErrorResponse": {
+        "models.MatchFiltered": {
             "type": "object",
             "properties": {
-                "error": {
+                "config_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MatchResult": {
+            "type": "object",
+            "properties": {
+                "config_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "excluded": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MatchExclusion"
+                    }
+                },
+                "experiments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExperimentAssignment"
+                    }
+                },
+                "fallback": {
+                    "type": "boolean"
+                },
+                "reason": {
                     "type": "string"
                 }
             }
@@ -650,14 +2963,259 @@ const docTemplate = `{
                 }
             }
         },
+        "models.PromoteRequest": {
+            "type": "object",
+            "required": [
+                "from"
+            ],
+            "properties": {
+                "configs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "specific_configs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to": {
+                    "description": "defaults to the environment after From",
+                    "type": "string"
+                }
+            }
+        },
+        "models.PromoteResult": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PromotionChange"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PromotionChange": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "diff": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "config or specific",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReadinessStatus": {
+            "type": "object",
+            "properties": {
+                "environment": {
+                    "type": "string"
+                },
+                "failed_files": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "ready or degraded",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ResolveResult": {
+            "type": "object",
+            "properties": {
+                "config_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "configs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Config"
+                    }
+                },
+                "excluded": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MatchExclusion"
+                    }
+                },
+                "experiments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExperimentAssignment"
+                    }
+                },
+                "fallback": {
+                    "type": "boolean"
+                },
+                "missing": {
+                    "description": "IDs without a stored configuration",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RolloutUpdate": {
+            "type": "object",
+            "required": [
+                "percent"
+            ],
+            "properties": {
+                "percent": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Rule": {
+            "type": "object",
+            "properties": {
+                "activeFrom": {
+                    "type": "string"
+                },
+                "activeUntil": {
+                    "type": "string"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "stop": {
+                    "type": "boolean"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "when": {
+                    "$ref": "#/definitions/models.Condition"
+                }
+            }
+        },
+        "models.ScheduleTransition": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "config_id": {
+                    "type": "string"
+                },
+                "config_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "event": {
+                    "description": "activate or deactivate",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "config, mapping or rule",
+                    "type": "string"
+                },
+                "pattern": {
+                    "type": "string"
+                },
+                "rule": {
+                    "description": "host, url, page or rule",
+                    "type": "string"
+                },
+                "specific_config": {
+                    "type": "string"
+                }
+            }
+        },
         "models.SpecificConfig": {
             "type": "object",
             "properties": {
+                "annotations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
                 "datasource": {
                     "$ref": "#/definitions/models.DataSource"
                 },
+                "description": {
+                    "type": "string"
+                },
+                "exclude": {
+                    "$ref": "#/definitions/models.Exclude"
+                },
+                "fallback": {
+                    "$ref": "#/definitions/models.Fallback"
+                },
                 "id": {
                     "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Rule"
+                    }
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "weights": {
+                    "$ref": "#/definitions/models.Weights"
                 }
             }
         },
@@ -673,6 +3231,40 @@ const docTemplate = `{
                     "example": "johndoe"
                 }
             }
+        },
+        "models.Variant": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Weights": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "rule": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLoadReport godoc
+// @Summary Report the startup load
+// @Description Lists how many files every environment loaded at startup and, in lenient load mode, every file that
+// @Description was skipped with the reason
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.LoadReport
+// @Security BearerAuth
+// @Router /api/admin/load-report [get]
+func GetLoadReport(service *services.EnvironmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.LoadReport())
+	}
+}
+
+// GetReadiness godoc
+// @Summary Readiness probe
+// @Description Reports ready, or degraded when files of the served environment were skipped at startup. The
+// @Description server keeps serving in both cases, so the status code is always 200
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.ReadinessStatus
+// @Router /ready [get]
+func GetReadiness(service *services.EnvironmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.Readiness())
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize limits the size of an uploaded archive.
+const maxImportSize = 32 << 20
+
+// ExportConfigs godoc
+// @Summary Export all configurations
+// @Description Streams every configuration and specific configuration as YAML in a tar.gz or zip archive,
+// @Description together with a manifest.yaml listing the SHA-256 checksum of every file
+// @Tags archive
+// @Produce application/gzip
+// @Produce application/zip
+// @Param format query string false "tar.gz (default) or zip"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/export [get]
+func ExportConfigs(service *services.ArchiveService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", services.ArchiveTarGz)
+		contentType := "application/gzip"
+		switch format {
+		case services.ArchiveTarGz:
+		case services.ArchiveZip:
+			contentType = "application/zip"
+		default:
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Format must be tar.gz or zip"})
+			return
+		}
+
+		filename := fmt.Sprintf("configs-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+		if err := service.Export(c.Writer, format); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// ImportConfigs godoc
+// @Summary Import configurations from an archive
+// @Description Imports a tar.gz or zip archive produced by GET /api/export. Every file is checked against the
+// @Description manifest and validated before anything is changed. With dry_run the result is returned at once;
+// @Description otherwise the import is proposed as a change request and applied all or nothing once another user
+// @Description approves it. Requires the publisher role
+// @Tags archive
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Archive"
+// @Param mode query string false "What to do with existing IDs: fail (default), skip or overwrite"
+// @Param dry_run query bool false "Only report what would be created, updated and skipped"
+// @Success 200 {object} models.ImportResult "Dry run"
+// @Success 202 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/import [post]
+func ImportConfigs(service *services.ArchiveService, changes *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+		// The archive is either a multipart upload or the raw request body
+		var reader io.Reader = c.Request.Body
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			file, _, err := c.Request.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "The archive must be uploaded as the file field"})
+				return
+			}
+			defer file.Close()
+			reader = file
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Archive could not be read: " + err.Error()})
+			return
+		}
+
+		proposal, err := service.ReadArchive(data, c.DefaultQuery("mode", services.ImportFail))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		if c.Query("dry_run") == "true" {
+			result, err := service.Import(c.GetString("username"), proposal, true)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, result)
+			return
+		}
+
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:      models.ChangeKindImport,
+			Operation: models.ChangeApply,
+			Import:    &proposal,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, request)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChangeRequests godoc
+// @Summary List change requests
+// @Description Lists change requests with the given status, pending ones by default; status=all lists every request
+// @Tags changes
+// @Produce json
+// @Param status query string false "pending, approved, rejected or all"
+// @Success 200 {array} models.ChangeRequest
+// @Security BearerAuth
+// @Router /api/changes [get]
+func GetChangeRequests(service *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.DefaultQuery("status", models.ChangePending)
+		if status == "all" {
+			status = ""
+		}
+		c.JSON(http.StatusOK, service.GetChangeRequests(status))
+	}
+}
+
+// GetChangeRequestByID godoc
+// @Summary Get a change request
+// @Tags changes
+// @Produce json
+// @Param id path string true "Change request ID"
+// @Success 200 {object} models.ChangeRequest
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/changes/{id} [get]
+func GetChangeRequestByID(service *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, err := service.GetChangeRequestByID(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Change request not found"})
+			return
+		}
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+// ProposeChange godoc
+// @Summary Propose a change
+// @Description Proposes deleting or archiving a configuration (kind config), creating, updating or deleting a
+// @Description specific configuration (kind specific) or an experiment (kind experiment), or a rollout, import or
+// @Description promotion. Anything but a specific config change or a config delete needs the publisher role. The
+// @Description change is applied once a different user with the approver role approves it
+// @Tags changes
+// @Accept json
+// @Produce json
+// @Param proposal body models.ChangeProposal true "Proposed change"
+// @Success 202 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/changes [post]
+func ProposeChange(service *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var proposal models.ChangeProposal
+		if err := c.ShouldBindJSON(&proposal); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format: " + err.Error()})
+			return
+		}
+
+		request, err := service.Propose(c.GetString("username"), c.GetStringSlice("roles"), proposal)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, request)
+	}
+}
+
+// ApproveChange godoc
+// @Summary Approve a change request
+// @Description Applies a pending change. Requires the approver role; the proposer can not approve their own change
+// @Tags changes
+// @Accept json
+// @Produce json
+// @Param id path string true "Change request ID"
+// @Param review body models.ChangeReview true "Review comment"
+// @Success 200 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/changes/{id}/approve [post]
+func ApproveChange(service *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var review models.ChangeReview
+		if err := c.ShouldBindJSON(&review); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "A review comment is required"})
+			return
+		}
+
+		request, err := service.Approve(c.GetString("username"), c.Param("id"), review.Comment)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+// RejectChange godoc
+// @Summary Reject a change request
+// @Description Closes a pending change without applying it. Requires the approver role; the proposer can not reject their own change
+// @Tags changes
+// @Accept json
+// @Produce json
+// @Param id path string true "Change request ID"
+// @Param review body models.ChangeReview true "Review comment"
+// @Success 200 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/changes/{id}/reject [post]
+func RejectChange(service *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var review models.ChangeReview
+		if err := c.ShouldBindJSON(&review); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "A review comment is required"})
+			return
+		}
+
+		request, err := service.Reject(c.GetString("username"), c.Param("id"), review.Comment)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, request)
+	}
+}
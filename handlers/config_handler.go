@@ -12,19 +12,30 @@ import (
 
 // GetAllConfigs godoc
 // @Summary Get all configurations
-// @Description Retrieves all configurations stored in memory
+// @Description Retrieves the configurations ordered by ID, optionally filtered by a label selector, in the flat
+// @Description form or, with format=resource, as apiVersion/kind/metadata/spec resources
 // @Tags configuration
 // @Accept json
 // @Produce json
+// @Param format query string false "Response form: flat (default) or resource"
+// @Param labelSelector query string false "Label selector, e.g. team=growth,brand in (x,y),!deprecated"
 // @Success 200 {array} models.Config "List of configurations"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/configuration/all [get]
 func GetAllConfigs(service *services.ConfigService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		configs, err := service.GetAllConfigs()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		resource, ok := resourceFormat(c)
+		if !ok {
+			return
+		}
+		selector, ok := labelSelector(c)
+		if !ok {
+			return
+		}
+		configs := service.FindConfigs(selector)
+		if resource {
+			c.JSON(http.StatusOK, configResources(configs))
 			return
 		}
 		c.JSON(http.StatusOK, configs)
@@ -33,35 +44,47 @@ func GetAllConfigs(service *services.ConfigService) gin.HandlerFunc {
 
 // GetConfigByID godoc
 // @Summary Get configuration by ID
-// @Description Retrieves a specific configuration by its ID
+// @Description Retrieves a specific configuration by its ID, in the flat form or, with format=resource,
+// @Description as an apiVersion/kind/metadata/spec resource
 // @Tags configuration
 // @Produce json
 // @Param id path string true "Configuration ID"
+// @Param format query string false "Response form: flat (default) or resource"
 // @Success 200 {object} models.Config
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/configuration/{id} [get]
 func GetConfigByID(service *services.ConfigService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		resource, ok := resourceFormat(c)
+		if !ok {
+			return
+		}
 		id := c.Param("id")
 		config, err := service.GetConfigByID(id)
 		if err != nil {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Config not found"})
 			return
 		}
+		if resource {
+			c.JSON(http.StatusOK, models.NewConfigResource(config))
+			return
+		}
 		c.JSON(http.StatusOK, config)
 	}
 }
 
 // AddConfig godoc
 // @Summary Add a new configuration
-// @Description Adds a new configuration to the system
+// @Description Saves a new configuration as a draft. It is served once it has been submitted and published.
+// @Description The body may be in the flat or the resource form
 // @Tags configuration
 // @Accept json
 // @Produce json
 // @Param config body models.Config true "Configuration"
-// @Success 201 {object} models.Config
+// @Success 201 {object} models.ConfigDraft
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
@@ -70,8 +93,8 @@ func AddConfig(service *services.ConfigService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Println("Received POST request to /api/configuration") // Added log statement
 
-		var config models.Config
-		if err := c.ShouldBindJSON(&config); err != nil {
+		config, err := bindConfig(c)
+		if err != nil {
 			log.Println("Error binding JSON:", err)
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("JSON binding error: %s", err.Error())})
 			return
@@ -79,27 +102,34 @@ func AddConfig(service *services.ConfigService) gin.HandlerFunc {
 
 		log.Println("Successfully bound JSON:", config) // Log the received config
 
-		// Attempt to add the configuration
-		if err := service.AddConfig(config); err != nil {
+		if _, err := service.ConfigStatus(config.ID); err == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Failed to add config: ID '%s' already exists", config.ID)})
+			return
+		}
+
+		// Attempt to save the configuration as a draft
+		draft, err := service.SaveDraft(c.GetString("username"), config)
+		if err != nil {
 			log.Println("Error adding config:", err)
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Failed to add config: %s", err.Error())})
 			return
 		}
 
-		log.Println("Config added successfully:", config)
-		c.JSON(http.StatusCreated, config)
+		log.Println("Config draft saved successfully:", config)
+		c.JSON(http.StatusCreated, draft)
 	}
 }
 
 // UpdateConfig godoc
 // @Summary Update an existing configuration
-// @Description Updates an existing configuration by ID
+// @Description Saves the changes as the draft of the configuration; the published revision keeps being served
+// @Description until the draft is published. The body may be in the flat or the resource form
 // @Tags configuration
 // @Accept json
 // @Produce json
 // @Param id path string true "Configuration ID"
 // @Param config body models.Config true "Updated configuration"
-// @Success 200 {object} models.Config
+// @Success 200 {object} models.ConfigDraft
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
@@ -108,39 +138,105 @@ func AddConfig(service *services.ConfigService) gin.HandlerFunc {
 func UpdateConfig(service *services.ConfigService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		var config models.Config
-		if err := c.ShouldBindJSON(&config); err != nil {
+		config, err := bindConfig(c)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 			return
 		}
-		if err := service.UpdateConfig(id, config); err != nil {
+		if _, err := service.ConfigStatus(id); err != nil {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Config not found"})
 			return
 		}
-		c.JSON(http.StatusOK, config)
+
+		config.ID = id
+		draft, err := service.SaveDraft(c.GetString("username"), config)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, draft)
 	}
 }
 
 // DeleteConfig godoc
 // @Summary Delete a configuration
-// @Description Deletes a specific configuration by ID
+// @Description Proposes deleting a configuration by ID. It is deleted once another user approves the change request
 // @Tags configuration
 // @Param id path string true "Configuration ID"
-// @Success 200 {object} models.MessageResponse
+// @Success 202 {object} models.ChangeRequest
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/configuration/{id} [delete]
-func DeleteConfig(service *services.ConfigService) gin.HandlerFunc {
+func DeleteConfig(changes *services.ChangeRequestService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		// Veritabanında id'nin var olup olmadığını kontrol et
-		if err := service.DeleteConfig(id); err != nil {
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:      models.ChangeKindConfig,
+			Operation: models.ChangeDelete,
+			TargetID:  id,
+		})
+		if err != nil {
 			// Eğer ID bulunamazsa, 404 döndür
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, request)
+	}
+}
+
+// SetConfigRollout godoc
+// @Summary Set the rollout percentage of a configuration
+// @Description Proposes serving the configuration to the given percentage of visitors, bucketed by visitor ID.
+// @Description It is applied once another user approves the change request. Rolling back to 0 stops serving
+// @Description the configuration at once and answers 200 with it; the change is recorded in the audit log.
+// @Description Requires the publisher role
+// @Tags configuration
+// @Accept json
+// @Produce json
+// @Param id path string true "Configuration ID"
+// @Param rollout body models.RolloutUpdate true "Rollout percentage (0-100)"
+// @Success 200 {object} models.Config
+// @Success 202 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/rollout [put]
+func SetConfigRollout(service *services.ConfigService, changes *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var update models.RolloutUpdate
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		if _, err := service.GetConfigByID(id); err != nil {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Config not found"})
 			return
 		}
-		// Silme başarılı ise
-		c.JSON(http.StatusOK, models.MessageResponse{Message: "Config deleted"})
+
+		// A rollback has to work while nobody is around to approve it
+		if update.Percent != nil && *update.Percent == 0 {
+			config, err := service.SetRollout(c.GetString("username"), id, 0)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, config)
+			return
+		}
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:      models.ChangeKindRollout,
+			Operation: models.ChangeApply,
+			TargetID:  id,
+			Rollout:   update.Percent,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, request)
 	}
 }
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEnvironments godoc
+// @Summary List environments
+// @Description Lists the environments in promotion order and marks the one this server serves
+// @Tags environment
+// @Produce json
+// @Success 200 {array} models.EnvironmentSummary
+// @Security BearerAuth
+// @Router /api/environments [get]
+func GetEnvironments(service *services.EnvironmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.Summaries())
+	}
+}
+
+// Promote godoc
+// @Summary Promote configurations to the next environment
+// @Description Copies the published revisions of the selected configs and specific configs from one environment
+// @Description to the next. With dry_run the planned creates and updates are returned with a YAML diff and nothing
+// @Description is written; otherwise the promotion is proposed as a change request and applied once another user
+// @Description approves it. Requires the publisher role
+// @Tags environment
+// @Accept json
+// @Produce json
+// @Param promotion body models.PromoteRequest true "Source environment and selected IDs"
+// @Success 200 {object} models.PromoteResult "Dry run"
+// @Success 202 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/promote [post]
+func Promote(service *services.EnvironmentService, changes *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PromoteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format: " + err.Error()})
+			return
+		}
+
+		if req.DryRun {
+			result, err := service.Promote(c.GetString("username"), req)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, result)
+			return
+		}
+
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:      models.ChangeKindPromotion,
+			Operation: models.ChangeApply,
+			Promotion: &req,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, request)
+	}
+}
+
+// GetDrift godoc
+// @Summary Report drift between environments
+// @Description Lists every config and specific config that is missing from an environment or differs between
+// @Description environments, with a checksum of each revision. Environments are read again from disk
+// @Tags environment
+// @Produce json
+// @Success 200 {object} models.DriftReport
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/drift [get]
+func GetDrift(service *services.EnvironmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := service.Drift()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAllExperiments godoc
+// @Summary Get all experiments
+// @Description Retrieves all A/B experiments
+// @Tags experiment
+// @Produce json
+// @Success 200 {array} models.Experiment
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/experiment/all [get]
+func GetAllExperiments(service *services.ExperimentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		experiments, err := service.GetAllExperiments()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, experiments)
+	}
+}
+
+// GetExperimentByID godoc
+// @Summary Get experiment by ID
+// @Description Retrieves an A/B experiment by its ID
+// @Tags experiment
+// @Produce json
+// @Param id path string true "Experiment ID"
+// @Success 200 {object} models.Experiment
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/experiment/{id} [get]
+func GetExperimentByID(service *services.ExperimentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		experiment, err := service.GetExperimentByID(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Experiment not found"})
+			return
+		}
+		c.JSON(http.StatusOK, experiment)
+	}
+}
+
+// AddExperiment godoc
+// @Summary Add new experiment
+// @Description Proposes an A/B experiment. Mappings reference it as "experiment:<id>" and visitors are bucketed
+// @Description into its weighted variants by hashing their visitor ID with the salt. It is added once another
+// @Description user approves the change request. Requires the publisher role
+// @Tags experiment
+// @Accept json
+// @Produce json
+// @Param experiment body models.Experiment true "Experiment"
+// @Success 202 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/experiment [post]
+func AddExperiment(changes *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var experiment models.Experiment
+		if err := c.ShouldBindJSON(&experiment); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format: " + err.Error()})
+			return
+		}
+
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:       models.ChangeKindExperiment,
+			Operation:  models.ChangeCreate,
+			Experiment: &experiment,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Failed to add experiment: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, request)
+	}
+}
+
+// UpdateExperiment godoc
+// @Summary Update experiment
+// @Description Proposes a change to an A/B experiment. Changing the salt, holdout or weights reassigns visitors.
+// @Description It is applied once another user approves the change request. Requires the publisher role
+// @Tags experiment
+// @Accept json
+// @Produce json
+// @Param id path string true "Experiment ID"
+// @Param experiment body models.Experiment true "Updated Experiment"
+// @Success 202 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/experiment/{id} [put]
+func UpdateExperiment(changes *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var experiment models.Experiment
+		if err := c.ShouldBindJSON(&experiment); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:       models.ChangeKindExperiment,
+			Operation:  models.ChangeUpdate,
+			TargetID:   id,
+			Experiment: &experiment,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, request)
+	}
+}
+
+// DeleteExperiment godoc
+// @Summary Delete experiment
+// @Description Proposes deleting an A/B experiment by ID. It is deleted once another user approves the change
+// @Description request. Requires the publisher role
+// @Tags experiment
+// @Param id path string true "Experiment ID"
+// @Success 202 {object} models.ChangeRequest
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/experiment/{id} [delete]
+func DeleteExperiment(changes *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:      models.ChangeKindExperiment,
+			Operation: models.ChangeDelete,
+			TargetID:  c.Param("id"),
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, request)
+	}
+}
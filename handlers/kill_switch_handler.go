@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetKillSwitches godoc
+// @Summary Get kill switches
+// @Description Lists the global, per-host and per-config kill switches that are on
+// @Tags killswitch
+// @Produce json
+// @Success 200 {object} models.KillSwitches
+// @Security BearerAuth
+// @Router /api/killswitch [get]
+func GetKillSwitches(service *services.KillSwitchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.State())
+	}
+}
+
+// ToggleKillSwitch godoc
+// @Summary Toggle a kill switch
+// @Description Turns the global, a per-host or a per-config kill switch on or off. Killed configs stay stored
+// @Description but are no longer served. The switch changes at once and every change is recorded in the audit
+// @Description log. Requires the publisher role
+// @Tags killswitch
+// @Accept json
+// @Produce json
+// @Param toggle body models.KillSwitchToggle true "Scope (global, host or config), target and new state"
+// @Success 200 {object} models.KillSwitches
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/killswitch [put]
+func ToggleKillSwitch(service *services.KillSwitchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var toggle models.KillSwitchToggle
+		if err := c.ShouldBindJSON(&toggle); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format: " + err.Error()})
+			return
+		}
+
+		state, err := service.Toggle(c.GetString("username"), toggle)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+// GetAuditLog godoc
+// @Summary Get the audit log
+// @Description Lists audited changes such as kill switch toggles and rollout changes, newest first
+// @Tags audit
+// @Produce json
+// @Success 200 {array} models.AuditEntry
+// @Security BearerAuth
+// @Router /api/audit [get]
+func GetAuditLog(audit *services.AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, audit.Entries())
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindMatchRequest builds a MatchRequest from the query parameters shared by
+// the matching endpoints. It writes a 400 response and returns false when the
+// request is unusable.
+func bindMatchRequest(c *gin.Context) (services.MatchRequest, bool) {
+	req := services.MatchRequest{
+		Host: c.Query("host"),
+		URL:  c.Query("url"),
+		Page: c.Query("page"),
+	}
+
+	// The runtime forwards the visitor's raw query string in "query"
+	rawQuery := c.Query("query")
+
+	// A full href fills whatever the explicit parameters leave empty
+	if href := c.Query("href"); href != "" {
+		u, err := url.Parse(href)
+		if err != nil || u.Host == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid href: an absolute URL is required"})
+			return req, false
+		}
+		if req.Host == "" {
+			req.Host = u.Host
+		}
+		if req.URL == "" {
+			req.URL = u.EscapedPath()
+		}
+		if rawQuery == "" {
+			rawQuery = u.RawQuery
+		}
+	}
+
+	if rawQuery != "" {
+		values, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid query string: " + err.Error()})
+			return req, false
+		}
+		req.Query = values
+	}
+
+	// Validate at least one parameter is provided
+	if req.IsEmpty() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "At least one parameter (href, host, url, page or query) is required",
+		})
+		return req, false
+	}
+
+	bindRequestContext(c, &req)
+	return req, true
+}
+
+// bindRequestContext fills the visitor context from the headers and cookies
+// the runtime forwards. The ua, device, lang and visitor query parameters
+// override what the headers say.
+func bindRequestContext(c *gin.Context, req *services.MatchRequest) {
+	req.Headers = c.Request.Header
+	req.VisitorID = c.DefaultQuery("visitor", c.GetHeader("X-Visitor-ID"))
+	req.UserAgent = c.DefaultQuery("ua", c.GetHeader("User-Agent"))
+
+	req.Device = strings.ToLower(c.Query("device"))
+	if req.Device == "" {
+		req.Device = services.ClassifyUserAgent(req.UserAgent)
+	}
+
+	req.Languages = services.ParseAcceptLanguage(c.DefaultQuery("lang", c.GetHeader("Accept-Language")))
+
+	cookies := c.Request.Cookies()
+	req.Cookies = make(map[string]string, len(cookies))
+	for _, cookie := range cookies {
+		req.Cookies[cookie.Name] = cookie.Value
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resourceFormat reports whether the response is requested in the resource
+// form with ?format=resource; the flat form is the default. It writes a 400
+// response and returns false as ok for an unknown format.
+func resourceFormat(c *gin.Context) (resource bool, ok bool) {
+	switch c.Query("format") {
+	case "", "flat":
+		return false, true
+	case "resource":
+		return true, true
+	}
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid format: expected flat or resource"})
+	return false, false
+}
+
+// labelSelector parses the labelSelector query parameter. It writes a 400
+// response and returns false when the selector is invalid.
+func labelSelector(c *gin.Context) (services.LabelSelector, bool) {
+	selector, err := services.ParseLabelSelector(c.Query("labelSelector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return nil, false
+	}
+	return selector, true
+}
+
+// hasAPIVersion reports whether a JSON body is in the resource form.
+func hasAPIVersion(body []byte) bool {
+	var probe struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	return json.Unmarshal(body, &probe) == nil && probe.APIVersion != ""
+}
+
+// bindConfig reads a configuration from the request body in the flat or the
+// resource form.
+func bindConfig(c *gin.Context) (models.Config, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return models.Config{}, err
+	}
+
+	if !hasAPIVersion(body) {
+		var config models.Config
+		err := json.Unmarshal(body, &config)
+		return config, err
+	}
+	var resource models.ConfigResource
+	if err := json.Unmarshal(body, &resource); err != nil {
+		return models.Config{}, err
+	}
+	return resource.Config()
+}
+
+// bindSpecificConfig reads a specific configuration from the request body in
+// the flat or the resource form.
+func bindSpecificConfig(c *gin.Context) (models.SpecificConfig, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return models.SpecificConfig{}, err
+	}
+
+	if !hasAPIVersion(body) {
+		var config models.SpecificConfig
+		err := json.Unmarshal(body, &config)
+		return config, err
+	}
+	var resource models.SpecificConfigResource
+	if err := json.Unmarshal(body, &resource); err != nil {
+		return models.SpecificConfig{}, err
+	}
+	return resource.SpecificConfig()
+}
+
+// configResources converts configurations to the resource form.
+func configResources(configs []models.Config) []models.ConfigResource {
+	resources := make([]models.ConfigResource, len(configs))
+	for i, config := range configs {
+		resources[i] = models.NewConfigResource(config)
+	}
+	return resources
+}
+
+// specificConfigResources converts specific configurations to the resource
+// form.
+func specificConfigResources(configs []models.SpecificConfig) []models.SpecificConfigResource {
+	resources := make([]models.SpecificConfigResource, len(configs))
+	for i, config := range configs {
+		resources[i] = models.NewSpecificConfigResource(config)
+	}
+	return resources
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSchedule godoc
+// @Summary List upcoming schedule transitions
+// @Description Lists the upcoming activations and deactivations of configurations and specific config mappings, earliest first
+// @Tags schedule
+// @Produce json
+// @Success 200 {array} models.ScheduleTransition
+// @Security BearerAuth
+// @Router /api/schedule [get]
+func GetSchedule(configService *services.ConfigService, specificService *services.SpecificConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transitions := services.UpcomingTransitions(configService, specificService, specificService.Now())
+		if transitions == nil {
+			transitions = []models.ScheduleTransition{}
+		}
+		c.JSON(http.StatusOK, transitions)
+	}
+}
@@ -10,18 +10,29 @@ import (
 
 // GetAllSpecificConfigs godoc
 // @Summary Get all specific configurations
-// @Description Retrieves all specific configurations
+// @Description Retrieves the specific configurations ordered by ID, optionally filtered by a label selector, in
+// @Description the flat form or, with format=resource, as apiVersion/kind/metadata/spec resources
 // @Tags specific
 // @Produce json
+// @Param format query string false "Response form: flat (default) or resource"
+// @Param labelSelector query string false "Label selector, e.g. team=growth,brand in (x,y),!deprecated"
 // @Success 200 {array} models.SpecificConfig
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/specific/all [get]
 func GetAllSpecificConfigs(service *services.SpecificConfigService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		configs, err := service.GetAllSpecificConfigs()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		resource, ok := resourceFormat(c)
+		if !ok {
+			return
+		}
+		selector, ok := labelSelector(c)
+		if !ok {
+			return
+		}
+		configs := service.FindSpecificConfigs(selector)
+		if resource {
+			c.JSON(http.StatusOK, specificConfigResources(configs))
 			return
 		}
 		c.JSON(http.StatusOK, configs)
@@ -30,139 +41,208 @@ func GetAllSpecificConfigs(service *services.SpecificConfigService) gin.HandlerF
 
 // GetSpecificConfigByID godoc
 // @Summary Get specific configuration by ID
-// @Description Retrieves a specific configuration by its ID
+// @Description Retrieves a specific configuration by its ID, in the flat form or, with format=resource,
+// @Description as an apiVersion/kind/metadata/spec resource
 // @Tags specific
 // @Produce json
 // @Param id path string true "Configuration ID"
+// @Param format query string false "Response form: flat (default) or resource"
 // @Success 200 {object} models.SpecificConfig
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/specific/{id} [get]
 func GetSpecificConfigByID(service *services.SpecificConfigService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		resource, ok := resourceFormat(c)
+		if !ok {
+			return
+		}
 		id := c.Param("id")
 		config, err := service.GetSpecificConfigByID(id)
 		if err != nil {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Specific config not found"})
 			return
 		}
+		if resource {
+			c.JSON(http.StatusOK, models.NewSpecificConfigResource(config))
+			return
+		}
 		c.JSON(http.StatusOK, config)
 	}
 }
 
 // UpdateSpecificConfig godoc
 // @Summary Update specific configuration
-// @Description Updates an existing specific configuration
+// @Description Proposes an update of a specific configuration. It is applied once another user approves the change request.
+// @Description The body may be in the flat or the resource form
 // @Tags specific
 // @Accept json
 // @Produce json
 // @Param id path string true "Configuration ID"
 // @Param config body models.SpecificConfig true "Updated Configuration"
-// @Success 200 {object} models.SpecificConfig
+// @Success 202 {object} models.ChangeRequest
 // @Failure 400 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/specific/{id} [put]
-func UpdateSpecificConfig(service *services.SpecificConfigService) gin.HandlerFunc {
+func UpdateSpecificConfig(changes *services.ChangeRequestService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		var config models.SpecificConfig
-		if err := c.ShouldBindJSON(&config); err != nil {
+		config, err := bindSpecificConfig(c)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 			return
 		}
 
-		if err := service.UpdateSpecificConfig(id, config); err != nil {
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:           models.ChangeKindSpecific,
+			Operation:      models.ChangeUpdate,
+			TargetID:       id,
+			SpecificConfig: &config,
+		})
+		if err != nil {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, config)
+		c.JSON(http.StatusAccepted, request)
 	}
 }
 
 // DeleteSpecificConfig godoc
 // @Summary Delete specific configuration
-// @Description Deletes a specific configuration by ID
+// @Description Proposes deleting a specific configuration by ID. It is deleted once another user approves the change request
 // @Tags specific
 // @Param id path string true "Configuration ID"
-// @Success 200 {object} models.MessageResponse
+// @Success 202 {object} models.ChangeRequest
 // @Failure 404 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/specific/{id} [delete]
-func DeleteSpecificConfig(service *services.SpecificConfigService) gin.HandlerFunc {
+func DeleteSpecificConfig(changes *services.ChangeRequestService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := c.Param("id")
-		if err := service.DeleteSpecificConfig(id); err != nil {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Specific config not found"})
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:      models.ChangeKindSpecific,
+			Operation: models.ChangeDelete,
+			TargetID:  c.Param("id"),
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, models.MessageResponse{Message: "Specific config deleted"})
+		c.JSON(http.StatusAccepted, request)
 	}
 }
 
 // GetSpecificConfigs godoc
 // @Summary Get matching configurations
-// @Description Get configuration IDs based on host, url or page
+// @Description Get configuration IDs based on host, url or page. Datasource keys may be exact values,
+// @Description host wildcards (*.example.com), url globs (/products/*, /docs/**), named url parameters
+// @Description (/products/:id) or anchored regexes prefixed with "re:". More specific keys rank first on equal score.
+// @Description Hosts and paths are normalized (case, punycode, default ports, duplicate and trailing slashes,
+// @Description percent-encoding) before matching, and so are the datasource keys. When nothing matches, the
+// @Description per-host or global fallback IDs are returned with fallback=true. Experiment references are replaced
+// @Description by the IDs of the visitor's variant, which is reported under experiments.
 // @Tags specific
 // @Produce json
+// @Param href query string false "Full visitor URL; fills host, url and query when they are not given"
 // @Param host query string false "Target host"
 // @Param url query string false "Target URL path"
 // @Param page query string false "Target page name"
-// @Success 200 {object} map[string][]string
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Param query query string false "Raw query string of the visitor's request, used by rule conditions"
+// @Param ua query string false "Visitor user agent, defaults to the User-Agent header"
+// @Param device query string false "Device class (mobile, tablet, desktop, tv, bot), defaults to the one derived from the user agent"
+// @Param lang query string false "Visitor languages, defaults to the Accept-Language header"
+// @Param visitor query string false "Visitor ID experiments bucket on, defaults to the X-Visitor-ID header"
+// @Success 200 {object} models.MatchResult "Matching IDs, the fallback IDs, or an empty list with a reason"
+// @Failure 400 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/specific [get]
 func GetSpecificConfigs(service *services.SpecificConfigService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		host := c.Query("host")
-		url := c.Query("url")
-		page := c.Query("page")
+		req, ok := bindMatchRequest(c)
+		if !ok {
+			return
+		}
 
-		// Validate at least one parameter is provided
-		if host == "" && url == "" && page == "" {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "At least one parameter (host, url or page) is required",
-			})
+		c.JSON(http.StatusOK, service.GetMatchingConfigs(req))
+	}
+}
+
+// ExplainSpecificConfigs godoc
+// @Summary Explain configuration matching
+// @Description Shows, for every candidate config ID, which specific config and rule (host, url or page pattern, or compound rule)
+// @Description contributed how many points, and the final order including tie-breaks by specificity and ID
+// @Tags specific
+// @Produce json
+// @Param href query string false "Full visitor URL; fills host, url and query when they are not given"
+// @Param host query string false "Target host"
+// @Param url query string false "Target URL path"
+// @Param page query string false "Target page name"
+// @Param query query string false "Raw query string of the visitor's request, used by rule conditions"
+// @Param ua query string false "Visitor user agent, defaults to the User-Agent header"
+// @Param device query string false "Device class (mobile, tablet, desktop, tv, bot), defaults to the one derived from the user agent"
+// @Param lang query string false "Visitor languages, defaults to the Accept-Language header"
+// @Param visitor query string false "Visitor ID experiments bucket on, defaults to the X-Visitor-ID header"
+// @Success 200 {object} models.MatchExplanation
+// @Failure 400 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/specific/explain [get]
+func ExplainSpecificConfigs(service *services.SpecificConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := bindMatchRequest(c)
+		if !ok {
 			return
 		}
 
-		configIDs, err := service.GetMatchingConfigs(host, url, page)
-		if err != nil {
-			if err.Error() == "no matching configurations found" {
-				c.JSON(http.StatusNotFound, models.ErrorResponse{
-					Error: "No configurations found for the specified criteria",
-				})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error: "Failed to retrieve configurations: " + err.Error(),
-			})
+		c.JSON(http.StatusOK, service.ExplainMatch(req))
+	}
+}
+
+// ResolveSpecificConfigs godoc
+// @Summary Resolve matching configurations
+// @Description Matches the request like GET /api/specific and returns the matched configurations themselves,
+// @Description in ranking order, together with any exclusions that vetoed config IDs and the assigned experiment variants
+// @Tags specific
+// @Produce json
+// @Param href query string false "Full visitor URL; fills host, url and query when they are not given"
+// @Param host query string false "Target host"
+// @Param url query string false "Target URL path"
+// @Param page query string false "Target page name"
+// @Param query query string false "Raw query string of the visitor's request"
+// @Param visitor query string false "Visitor ID experiments bucket on, defaults to the X-Visitor-ID header"
+// @Success 200 {object} models.ResolveResult
+// @Failure 400 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/specific/resolve [get]
+func ResolveSpecificConfigs(resolver *services.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := bindMatchRequest(c)
+		if !ok {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"config_ids": configIDs})
+		c.JSON(http.StatusOK, resolver.Resolve(req))
 	}
 }
 
 // AddSpecificConfig godoc
 // @Summary Add new specific configuration
-// @Description Add a new specific configuration mapping
+// @Description Proposes a new specific configuration mapping. It is added once another user approves the change request.
+// @Description The body may be in the flat or the resource form
 // @Tags specific
 // @Accept json
 // @Produce json
 // @Param config body models.SpecificConfig true "Specific Configuration"
-// @Success 201 {object} models.SpecificConfig
+// @Success 202 {object} models.ChangeRequest
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
 // @Router /api/specific [post]
-func AddSpecificConfig(service *services.SpecificConfigService) gin.HandlerFunc {
+func AddSpecificConfig(changes *services.ChangeRequestService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var config models.SpecificConfig
-
 		// Bind and validate request body
-		if err := c.ShouldBindJSON(&config); err != nil {
+		config, err := bindSpecificConfig(c)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Error: "Invalid request format: " + err.Error(),
 			})
@@ -177,23 +257,37 @@ func AddSpecificConfig(service *services.SpecificConfigService) gin.HandlerFunc
 			return
 		}
 
+		// A config holding only a fallback or only exclusions is valid: it
+		// serves its IDs when nothing else matches or vetoes IDs other
+		// configs match
+		hasFallback := config.Fallback != nil &&
+			(len(config.Fallback.IDs) > 0 || len(config.Fallback.Hosts) > 0)
+		hasExclude := config.Exclude != nil &&
+			(len(config.Exclude.Pages) > 0 || len(config.Exclude.URLs) > 0 || len(config.Exclude.Hosts) > 0)
 		if len(config.DataSource.Pages) == 0 &&
 			len(config.DataSource.URLs) == 0 &&
-			len(config.DataSource.Hosts) == 0 {
+			len(config.DataSource.Hosts) == 0 &&
+			len(config.Rules) == 0 &&
+			!hasFallback && !hasExclude {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "At least one datasource mapping is required",
+				Error: "At least one datasource mapping, rule, fallback or exclusion is required",
 			})
 			return
 		}
 
-		// Add configuration
-		if err := service.AddSpecificConfig(config); err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		// Propose the configuration
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:           models.ChangeKindSpecific,
+			Operation:      models.ChangeCreate,
+			SpecificConfig: &config,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Error: "Failed to add configuration: " + err.Error(),
 			})
 			return
 		}
 
-		c.JSON(http.StatusCreated, config)
+		c.JSON(http.StatusAccepted, request)
 	}
 }
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"net/http"
+	"ssd-assignment-api/models"
+	"ssd-assignment-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAllDrafts godoc
+// @Summary Get all configuration drafts
+// @Description Lists the drafts and the drafts in review of every configuration
+// @Tags workflow
+// @Produce json
+// @Success 200 {array} models.ConfigDraft
+// @Security BearerAuth
+// @Router /api/configuration/drafts [get]
+func GetAllDrafts(service *services.ConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.GetAllDrafts())
+	}
+}
+
+// GetDraft godoc
+// @Summary Get the draft of a configuration
+// @Tags workflow
+// @Produce json
+// @Param id path string true "Configuration ID"
+// @Success 200 {object} models.ConfigDraft
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/draft [get]
+func GetDraft(service *services.ConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, err := service.GetDraft(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Draft not found"})
+			return
+		}
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+// DiscardDraft godoc
+// @Summary Discard the draft of a configuration
+// @Tags workflow
+// @Param id path string true "Configuration ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/draft [delete]
+func DiscardDraft(service *services.ConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.DiscardDraft(c.GetString("username"), c.Param("id")); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, models.MessageResponse{Message: "Draft discarded"})
+	}
+}
+
+// GetConfigStatus godoc
+// @Summary Get the workflow state of a configuration
+// @Description Returns draft, in_review, published or archived
+// @Tags workflow
+// @Produce json
+// @Param id path string true "Configuration ID"
+// @Success 200 {object} models.ConfigStatus
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/status [get]
+func GetConfigStatus(service *services.ConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := service.ConfigStatus(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Config not found"})
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// DiffDraft godoc
+// @Summary Diff a draft against the published configuration
+// @Description Returns a line diff of the YAML of the published revision and the draft
+// @Tags workflow
+// @Produce json
+// @Param id path string true "Configuration ID"
+// @Success 200 {object} models.ConfigDiff
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/diff [get]
+func DiffDraft(service *services.ConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		diff, err := service.DiffDraft(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Config not found"})
+			return
+		}
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
+// SubmitDraft godoc
+// @Summary Submit a draft for review
+// @Tags workflow
+// @Produce json
+// @Param id path string true "Configuration ID"
+// @Success 200 {object} models.ConfigDraft
+// @Failure 400 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/submit [post]
+func SubmitDraft(service *services.ConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, err := service.SubmitDraft(c.GetString("username"), c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+// PublishDraft godoc
+// @Summary Publish a reviewed draft
+// @Description Makes a draft in review the served configuration. Requires the approver role; the user who
+// @Description edited or submitted the draft can not publish it
+// @Tags workflow
+// @Produce json
+// @Param id path string true "Configuration ID"
+// @Success 200 {object} models.Config
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/publish [post]
+func PublishDraft(service *services.ConfigService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config, err := service.PublishDraft(c.GetString("username"), c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, config)
+	}
+}
+
+// ArchiveConfig godoc
+// @Summary Archive a published configuration
+// @Description Proposes to stop serving the configuration but keep it so it can be edited and published again.
+// @Description It is archived once another user approves the change request. Requires the publisher role
+// @Tags workflow
+// @Param id path string true "Configuration ID"
+// @Success 202 {object} models.ChangeRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /api/configuration/{id}/archive [post]
+func ArchiveConfig(changes *services.ChangeRequestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, err := changes.Propose(c.GetString("username"), c.GetStringSlice("roles"), models.ChangeProposal{
+			Kind:      models.ChangeKindConfig,
+			Operation: models.ChangeArchive,
+			TargetID:  c.Param("id"),
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, request)
+	}
+}
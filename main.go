@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 	"ssd-assignment-api/handlers"
 	"ssd-assignment-api/services"
 	"time"
@@ -26,14 +27,71 @@ func main() {
 	// Initialize the in-memory ConfigService
 	// Specifying the file path
 
-	configService, err := services.NewConfigService("config_files")
+	auditLog, err := services.NewAuditLog("state/audit.log")
 	if err != nil {
-		log.Fatal("Error loading YAML: ", err)
+		log.Fatal("Audit log error: ", err)
 	}
-	specificService, err := services.NewSpecificConfigService("specific_configs")
+
+	// LOAD_MODE=lenient skips files that fail to load instead of aborting and
+	// LOAD_DERIVE_IDS=true takes a missing ID from the file name
+	loadMode, err := services.ParseLoadMode(os.Getenv("LOAD_MODE"))
+	if err != nil {
+		log.Fatal("Load mode error: ", err)
+	}
+	loadOptions := services.LoadOptions{Mode: loadMode, DeriveIDs: os.Getenv("LOAD_DERIVE_IDS") == "true"}
+
+	// Every environment of ENVIRONMENTS (dev,staging,prod by default) has its
+	// own storage; the server matches against APP_ENV, production by default
+	environments, err := services.NewEnvironmentService(
+		services.ParseEnvironments(os.Getenv("ENVIRONMENTS")), os.Getenv("APP_ENV"), loadOptions, auditLog)
 	if err != nil {
+		log.Fatal("Error loading YAML: ", err)
+	}
+	report := environments.LoadReport()
+	for _, diagnostic := range report.Failed {
+		log.Printf("Skipped %s (%s): %s", diagnostic.File, diagnostic.Environment, diagnostic.Error)
+	}
+	for _, diagnostic := range report.Warnings {
+		log.Printf("Warning for %s (%s): %s", diagnostic.File, diagnostic.Environment, diagnostic.Error)
+	}
+	configService := environments.Served().Configs
+	specificService := environments.Served().Specifics
+	normalizeOptions := services.NormalizeOptions{
+		StripWWW:  os.Getenv("MATCH_STRIP_WWW") == "true",
+		StripPort: os.Getenv("MATCH_STRIP_PORT") == "true",
+	}
+	if err := specificService.SetNormalizeOptions(normalizeOptions); err != nil {
 		log.Fatal("Specific config service error: ", err)
 	}
+	experimentService, err := services.NewExperimentService("experiments")
+	if err != nil {
+		log.Fatal("Experiment service error: ", err)
+	}
+	killSwitchService, err := services.NewKillSwitchService("state/kill_switches.yaml", normalizeOptions, auditLog)
+	if err != nil {
+		log.Fatal("Kill switch service error: ", err)
+	}
+	archiveService := services.NewArchiveService(configService, specificService, auditLog)
+	// Deletes, archives, specific configs, experiments, rollouts, imports and
+	// promotions are applied only after a second user with the approver role
+	// approves them; configs go through drafts and kill switches stay direct
+	changeService, err := services.NewChangeRequestService("change_requests", services.ChangeTargets{
+		Configs:      configService,
+		Specifics:    specificService,
+		Experiments:  experimentService,
+		Archives:     archiveService,
+		Environments: environments,
+	}, auditLog)
+	if err != nil {
+		log.Fatal("Change request service error: ", err)
+	}
+	if os.Getenv("ROLE_APPROVERS") == "" {
+		log.Print("Warning: ROLE_APPROVERS is not set, change requests can not be approved")
+	}
+	specificService.AddConfigFilter(killSwitchService)
+	specificService.AddConfigFilter(configService)
+	specificService.SetVariantAssigner(experimentService)
+	resolver := services.NewResolver(configService, specificService)
 
 	// Set up the Gin router
 	r := gin.Default()
@@ -41,7 +99,7 @@ func main() {
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // frontend adresi
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Visitor-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -50,6 +108,9 @@ func main() {
 	r.Use(cors.New(corsConfig))
 	// Apply the CORS middleware to all routes
 
+	// Readiness probe, reports degraded when files were skipped at startup
+	r.GET("/ready", handlers.GetReadiness(environments))
+
 	authRoutes := r.Group("/auth")
 	{
 		authRoutes.POST("/register", handlers.Register)
@@ -61,10 +122,19 @@ func main() {
 	configRoutes.Use(services.TokenAuthMiddleware())
 	{
 		configRoutes.GET("/all", handlers.GetAllConfigs(configService))
+		configRoutes.GET("/drafts", handlers.GetAllDrafts(configService))
 		configRoutes.GET("/:id", handlers.GetConfigByID(configService))
 		configRoutes.POST("/", handlers.AddConfig(configService))
 		configRoutes.PUT("/:id", handlers.UpdateConfig(configService))
-		configRoutes.DELETE("/:id", handlers.DeleteConfig(configService))
+		configRoutes.PUT("/:id/rollout", services.RequireRole(services.RolePublisher), handlers.SetConfigRollout(configService, changeService))
+		configRoutes.GET("/:id/status", handlers.GetConfigStatus(configService))
+		configRoutes.GET("/:id/draft", handlers.GetDraft(configService))
+		configRoutes.DELETE("/:id/draft", handlers.DiscardDraft(configService))
+		configRoutes.GET("/:id/diff", handlers.DiffDraft(configService))
+		configRoutes.POST("/:id/submit", handlers.SubmitDraft(configService))
+		configRoutes.POST("/:id/publish", services.RequireRole(services.RoleApprover), handlers.PublishDraft(configService))
+		configRoutes.POST("/:id/archive", services.RequireRole(services.RolePublisher), handlers.ArchiveConfig(changeService))
+		configRoutes.DELETE("/:id", handlers.DeleteConfig(changeService))
 	}
 
 	// Specific Configuration Routes
@@ -73,10 +143,74 @@ func main() {
 	{
 		specificRoutes.GET("/", handlers.GetSpecificConfigs(specificService))
 		specificRoutes.GET("/all", handlers.GetAllSpecificConfigs(specificService))
+		specificRoutes.GET("/explain", handlers.ExplainSpecificConfigs(specificService))
+		specificRoutes.GET("/resolve", handlers.ResolveSpecificConfigs(resolver))
 		specificRoutes.GET("/:id", handlers.GetSpecificConfigByID(specificService))
-		specificRoutes.POST("/", handlers.AddSpecificConfig(specificService))
-		specificRoutes.PUT("/:id", handlers.UpdateSpecificConfig(specificService))
-		specificRoutes.DELETE("/:id", handlers.DeleteSpecificConfig(specificService))
+		specificRoutes.POST("/", handlers.AddSpecificConfig(changeService))
+		specificRoutes.PUT("/:id", handlers.UpdateSpecificConfig(changeService))
+		specificRoutes.DELETE("/:id", handlers.DeleteSpecificConfig(changeService))
+	}
+
+	// Experiment Routes
+	experimentRoutes := r.Group("/api/experiment")
+	experimentRoutes.Use(services.TokenAuthMiddleware())
+	{
+		experimentRoutes.GET("/all", handlers.GetAllExperiments(experimentService))
+		experimentRoutes.GET("/:id", handlers.GetExperimentByID(experimentService))
+		experimentRoutes.POST("/", services.RequireRole(services.RolePublisher), handlers.AddExperiment(changeService))
+		experimentRoutes.PUT("/:id", services.RequireRole(services.RolePublisher), handlers.UpdateExperiment(changeService))
+		experimentRoutes.DELETE("/:id", services.RequireRole(services.RolePublisher), handlers.DeleteExperiment(changeService))
+	}
+
+	// Environment and Archive Routes
+	environmentRoutes := r.Group("/api")
+	environmentRoutes.Use(services.TokenAuthMiddleware())
+	{
+		environmentRoutes.GET("/environments", handlers.GetEnvironments(environments))
+		environmentRoutes.GET("/drift", handlers.GetDrift(environments))
+		environmentRoutes.POST("/promote", services.RequireRole(services.RolePublisher), handlers.Promote(environments, changeService))
+		environmentRoutes.GET("/export", handlers.ExportConfigs(archiveService))
+		environmentRoutes.POST("/import", services.RequireRole(services.RolePublisher), handlers.ImportConfigs(archiveService, changeService))
+	}
+
+	// Change Request Routes
+	changeRoutes := r.Group("/api/changes")
+	changeRoutes.Use(services.TokenAuthMiddleware())
+	{
+		changeRoutes.GET("/", handlers.GetChangeRequests(changeService))
+		changeRoutes.GET("/:id", handlers.GetChangeRequestByID(changeService))
+		changeRoutes.POST("/", handlers.ProposeChange(changeService))
+		changeRoutes.POST("/:id/approve", services.RequireRole(services.RoleApprover), handlers.ApproveChange(changeService))
+		changeRoutes.POST("/:id/reject", services.RequireRole(services.RoleApprover), handlers.RejectChange(changeService))
+	}
+
+	// Kill Switch Routes
+	killSwitchRoutes := r.Group("/api/killswitch")
+	killSwitchRoutes.Use(services.TokenAuthMiddleware())
+	{
+		killSwitchRoutes.GET("/", handlers.GetKillSwitches(killSwitchService))
+		killSwitchRoutes.PUT("/", services.RequireRole(services.RolePublisher), handlers.ToggleKillSwitch(killSwitchService))
+	}
+
+	// Audit Routes
+	auditRoutes := r.Group("/api/audit")
+	auditRoutes.Use(services.TokenAuthMiddleware())
+	{
+		auditRoutes.GET("/", handlers.GetAuditLog(auditLog))
+	}
+
+	// Admin Routes
+	adminRoutes := r.Group("/api/admin")
+	adminRoutes.Use(services.TokenAuthMiddleware())
+	{
+		adminRoutes.GET("/load-report", handlers.GetLoadReport(environments))
+	}
+
+	// Schedule Routes
+	scheduleRoutes := r.Group("/api/schedule")
+	scheduleRoutes.Use(services.TokenAuthMiddleware())
+	{
+		scheduleRoutes.GET("/", handlers.GetSchedule(configService, specificService))
 	}
 
 	// Swagger
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ArchiveManifest is stored as manifest.yaml in an export archive and lists
+// every document with its SHA-256 checksum.
+type ArchiveManifest struct {
+	Version    int            `yaml:"version" json:"version"`
+	ExportedAt time.Time      `yaml:"exportedAt" json:"exported_at"`
+	Files      []ArchiveEntry `yaml:"files" json:"files"`
+}
+
+type ArchiveEntry struct {
+	Path   string `yaml:"path" json:"path"`
+	Kind   string `yaml:"kind" json:"kind"` // config or specific
+	ID     string `yaml:"id" json:"id"`
+	SHA256 string `yaml:"sha256" json:"sha256"`
+}
+
+// ImportResult lists what an import did, or would do for a dry run, as
+// kind:id references.
+type ImportResult struct {
+	Mode    string   `json:"mode"`
+	DryRun  bool     `json:"dry_run"`
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Skipped []string `json:"skipped"`
+}
+
+// ImportProposal is an import waiting for approval: the validated documents
+// of the uploaded archive and the mode to apply them with.
+type ImportProposal struct {
+	Mode            string           `yaml:"mode" json:"mode"`
+	Configs         []Config         `yaml:"configs,omitempty" json:"configs,omitempty"`
+	SpecificConfigs []SpecificConfig `yaml:"specificConfigs,omitempty" json:"specific_configs,omitempty"`
+}
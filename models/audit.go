@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// AuditEntry records who changed what and when.
+type AuditEntry struct {
+	At     time.Time `json:"at"`
+	User   string    `json:"user"`
+	Action string    `json:"action"` // e.g. killswitch.on, config.rollout
+	Target string    `json:"target,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Change request kinds, operations and statuses.
+const (
+	ChangeKindConfig     = "config"
+	ChangeKindSpecific   = "specific"
+	ChangeKindExperiment = "experiment"
+	ChangeKindRollout    = "rollout"
+	ChangeKindImport     = "import"
+	ChangeKindPromotion  = "promotion"
+
+	ChangeCreate  = "create"
+	ChangeUpdate  = "update"
+	ChangeDelete  = "delete"
+	ChangeArchive = "archive" // stops serving a config, see ConfigService.ArchiveConfig
+	ChangeApply   = "apply"   // applies the payload of a rollout, import or promotion
+
+	ChangePending  = "pending"
+	ChangeApproved = "approved"
+	ChangeRejected = "rejected"
+)
+
+// ChangeProposal is the body of a proposed change. SpecificConfig is set for
+// specific config changes and Experiment for experiment changes; deletes and
+// archives only need TargetID. Configs are created and updated through
+// drafts, so config changes are only deletes and archives. Rollout, Import
+// and Promotion carry the payload of the other kinds, whose operation is
+// always apply.
+type ChangeProposal struct {
+	Kind           string          `yaml:"kind" json:"kind" binding:"required"`
+	Operation      string          `yaml:"operation" json:"operation" binding:"required"`
+	TargetID       string          `yaml:"targetId" json:"target_id"`
+	SpecificConfig *SpecificConfig `yaml:"specificConfig,omitempty" json:"specific_config,omitempty"`
+	Experiment     *Experiment     `yaml:"experiment,omitempty" json:"experiment,omitempty"`
+	Rollout        *int            `yaml:"rollout,omitempty" json:"rollout,omitempty"`
+	Import         *ImportProposal `yaml:"import,omitempty" json:"import,omitempty"`
+	Promotion      *PromoteRequest `yaml:"promotion,omitempty" json:"promotion,omitempty"`
+	Comment        string          `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// ChangeRequest is a proposed change to what is served that is only applied
+// once a different user with the approver role approves it.
+type ChangeRequest struct {
+	ID             string `yaml:"id" json:"id"`
+	ChangeProposal `yaml:",inline"`
+	Status         string     `yaml:"status" json:"status"`
+	ProposedBy     string     `yaml:"proposedBy" json:"proposed_by"`
+	ProposedAt     time.Time  `yaml:"proposedAt" json:"proposed_at"`
+	ReviewedBy     string     `yaml:"reviewedBy,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `yaml:"reviewedAt,omitempty" json:"reviewed_at,omitempty"`
+	ReviewComment  string     `yaml:"reviewComment,omitempty" json:"review_comment,omitempty"`
+}
+
+// ChangeReview is the body of an approval or rejection.
+type ChangeReview struct {
+	Comment string `json:"comment" binding:"required"`
+}
@@ -16,8 +16,22 @@ package models
 //}
 
 type Config struct {
-	ID      string   `yaml:"id"`
-	Actions []Action `yaml:"actions"`
+	ID       string   `yaml:"id"`
+	Actions  []Action `yaml:"actions"`
+	Schedule `yaml:",inline"`
+
+	// Rollout is the percentage of visitors the config is served to, bucketed
+	// by visitor ID; it is served to everyone when unset.
+	Rollout *int `yaml:"rollout,omitempty" json:"rollout,omitempty"`
+
+	ResourceMeta `yaml:",inline"`
+}
+
+// WithoutTimestamps returns the config without the fields the server
+// maintains.
+func (c Config) WithoutTimestamps() Config {
+	c.ResourceMeta = c.ResourceMeta.WithoutTimestamps()
+	return c
 }
 
 // Action represents a DOM manipulation action
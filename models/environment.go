@@ -0,0 +1,49 @@
+package models
+
+// EnvironmentSummary describes one environment of the promotion chain.
+type EnvironmentSummary struct {
+	Name            string `json:"name"`
+	Served          bool   `json:"served"` // the environment this server matches against
+	Configs         int    `json:"configs"`
+	SpecificConfigs int    `json:"specific_configs"`
+}
+
+// PromoteRequest selects the configs and specific configs to copy from one
+// environment to the next. With DryRun nothing is written.
+type PromoteRequest struct {
+	From            string   `yaml:"from" json:"from" binding:"required"`
+	To              string   `yaml:"to,omitempty" json:"to"` // defaults to the environment after From
+	Configs         []string `yaml:"configs,omitempty" json:"configs"`
+	SpecificConfigs []string `yaml:"specificConfigs,omitempty" json:"specific_configs"`
+	DryRun          bool     `yaml:"dryRun,omitempty" json:"dry_run"`
+}
+
+// PromotionChange is what promoting one document does to the target
+// environment: create, update or unchanged, with a line diff of its YAML.
+type PromotionChange struct {
+	Kind   string   `json:"kind"` // config or specific
+	ID     string   `json:"id"`
+	Action string   `json:"action"`
+	Diff   []string `json:"diff,omitempty"`
+}
+
+type PromoteResult struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	DryRun  bool              `json:"dry_run"`
+	Changes []PromotionChange `json:"changes"`
+}
+
+// DriftEntry is a document that differs between environments. Revisions maps
+// every environment to a checksum of the document, or "" where it is missing.
+type DriftEntry struct {
+	Kind      string            `json:"kind"`
+	ID        string            `json:"id"`
+	Revisions map[string]string `json:"revisions"`
+}
+
+type DriftReport struct {
+	Environments []string     `json:"environments"`
+	InSync       bool         `json:"in_sync"`
+	Drift        []DriftEntry `json:"drift"`
+}
@@ -0,0 +1,39 @@
+package models
+
+// Experiment splits the visitors of every mapping that references it as
+// "experiment:<id>" between weighted variants. Holdout is the percentage of
+// visitors that get none of the variants; Salt defaults to the ID and only
+// needs changing to reshuffle visitors between variants.
+//
+//	id: headline
+//	holdout: 10
+//	variants:
+//	  - name: control
+//	    ids: [A.yaml]
+//	    weight: 50
+//	  - name: ai-copy
+//	    ids: [B.yaml]
+//	    weight: 50
+type Experiment struct {
+	ID       string    `yaml:"id" json:"id"`
+	Salt     string    `yaml:"salt,omitempty" json:"salt,omitempty"`
+	Holdout  int       `yaml:"holdout,omitempty" json:"holdout,omitempty"`
+	Variants []Variant `yaml:"variants" json:"variants"`
+}
+
+// Variant is one arm of an experiment.
+type Variant struct {
+	Name   string      `yaml:"name" json:"name"`
+	IDs    StringSlice `yaml:"ids" json:"ids"`
+	Weight int         `yaml:"weight" json:"weight"`
+}
+
+// ExperimentAssignment records the variant a visitor was bucketed into so
+// analytics can attribute it. Variant is empty for visitors in the holdout.
+type ExperimentAssignment struct {
+	Experiment string   `json:"experiment"`
+	Variant    string   `json:"variant,omitempty"`
+	Holdout    bool     `json:"holdout,omitempty"`
+	ConfigIDs  []string `json:"config_ids,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// KillSwitches lists what is currently switched off. A killed config is never
+// served, a killed host gets no configs at all and the global switch stops
+// every config everywhere.
+type KillSwitches struct {
+	Global  bool     `yaml:"global" json:"global"`
+	Hosts   []string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	Configs []string `yaml:"configs,omitempty" json:"configs,omitempty"`
+}
+
+// KillSwitchToggle is the body of a kill switch change. Scope is global, host
+// or config; Target is the host or config ID and is ignored for global.
+type KillSwitchToggle struct {
+	Scope  string `json:"scope" binding:"required"`
+	Target string `json:"target"`
+	Killed bool   `json:"killed"`
+	Reason string `json:"reason"`
+}
+
+// RolloutUpdate is the body of a rollout percentage change. Percent is
+// required so an empty body does not switch a config off.
+type RolloutUpdate struct {
+	Percent *int `json:"percent" binding:"required"`
+}
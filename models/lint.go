@@ -0,0 +1,25 @@
+package models
+
+// Lint severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// LintIssue is a problem found in a configuration file. Line and Column are
+// 1-based and zero when the problem has no location in the file.
+type LintIssue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"` // parse, schema, selector, html, pattern, reference, duplicate-id or filename
+	Message  string `json:"message"`
+}
+
+// LintReport lists the issues found in the files that were checked.
+type LintReport struct {
+	Files  []string    `json:"files"`
+	Issues []LintIssue `json:"issues"`
+	Errors int         `json:"errors"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// LoadDiagnostic is a file, or a document of a file, that could not be
+// loaded at startup.
+type LoadDiagnostic struct {
+	Environment string `json:"environment,omitempty"`
+	Kind        string `json:"kind"` // config, draft, archived or specific
+	File        string `json:"file"`
+	Error       string `json:"error"`
+}
+
+// LoadReport summarizes the startup load. In lenient mode the files in Failed
+// were skipped and the server serves everything else. Warnings lists loaded
+// files with a problem, such as a name that does not match their ID.
+type LoadReport struct {
+	Mode     string           `json:"mode"` // strict or lenient
+	LoadedAt time.Time        `json:"loaded_at"`
+	Loaded   int              `json:"loaded"` // number of documents loaded
+	Failed   []LoadDiagnostic `json:"failed"`
+	Warnings []LoadDiagnostic `json:"warnings"`
+}
+
+// FileRename is a file the repair command renames to match its ID. Skipped
+// says why a file was left as it is.
+type FileRename struct {
+	From    string `json:"from"`
+	To      string `json:"to,omitempty"`
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// ReadinessStatus is the response of the readiness endpoint. Status is
+// degraded when files were skipped at startup.
+type ReadinessStatus struct {
+	Status      string `json:"status"` // ready or degraded
+	Environment string `json:"environment"`
+	FailedFiles int    `json:"failed_files"`
+}
@@ -0,0 +1,90 @@
+package models
+
+// MatchResult is the response of the matching endpoint. Fallback is set when
+// no mapping matched and the IDs come from a fallback; Reason explains why the
+// list is a fallback or empty.
+type MatchResult struct {
+	ConfigIDs   []string               `json:"config_ids"`
+	Experiments []ExperimentAssignment `json:"experiments,omitempty"`
+	Excluded    []MatchExclusion       `json:"excluded,omitempty"`
+	Fallback    bool                   `json:"fallback,omitempty"`
+	Reason      string                 `json:"reason,omitempty"`
+}
+
+// MatchExplanation describes how GetMatchingConfigs ranked the config IDs
+// for a request.
+type MatchExplanation struct {
+	Host       string                 `json:"host,omitempty"`
+	URL        string                 `json:"url,omitempty"`
+	Page       string                 `json:"page,omitempty"`
+	Query      map[string][]string    `json:"query,omitempty"`
+	Device     string                 `json:"device,omitempty"`
+	Language   string                 `json:"language,omitempty"`
+	ConfigIDs  []string               `json:"config_ids"`
+	Fallback   bool                   `json:"fallback,omitempty"`
+	Reason     string                 `json:"reason,omitempty"`
+	Candidates []CandidateExplanation `json:"candidates"`
+	Excluded   []MatchExclusion       `json:"excluded,omitempty"`
+	Filtered   []MatchFiltered        `json:"filtered,omitempty"`
+
+	// Experiments lists the variant assigned for every matched experiment
+	// reference; ConfigIDs holds the variant IDs in its place.
+	Experiments []ExperimentAssignment `json:"experiments,omitempty"`
+
+	// StoppedBelow is set when a matched mapping with stop discarded every
+	// matched mapping of lower priority; those are listed in Stopped.
+	StoppedBelow *int                `json:"stopped_below,omitempty"`
+	Stopped      []MatchContribution `json:"stopped,omitempty"`
+}
+
+// CandidateExplanation is a single ranked config ID with every mapping that
+// contributed to its score.
+type CandidateExplanation struct {
+	ConfigID      string              `json:"config_id"`
+	Rank          int                 `json:"rank"`
+	Priority      int                 `json:"priority"`
+	Score         int                 `json:"score"`
+	Broadness     int                 `json:"broadness"`
+	RankedBy      string              `json:"ranked_by,omitempty"` // priority, score, specificity or id, relative to the previous candidate
+	Contributions []MatchContribution `json:"contributions"`
+}
+
+// MatchExclusion records an exclusion key that vetoed a config ID.
+type MatchExclusion struct {
+	ConfigID       string `json:"config_id"`
+	SpecificConfig string `json:"specific_config"`
+	Rule           string `json:"rule"` // host, url or page
+	Pattern        string `json:"pattern"`
+}
+
+// MatchFiltered records a matched config ID that a filter, such as a
+// schedule, kept from being served.
+type MatchFiltered struct {
+	ConfigID string `json:"config_id"`
+	Reason   string `json:"reason"`
+}
+
+// ResolveResult is the response of the resolve endpoint: the matching
+// config IDs together with the configurations they refer to.
+type ResolveResult struct {
+	ConfigIDs   []string               `json:"config_ids"`
+	Configs     []Config               `json:"configs"`
+	Missing     []string               `json:"missing,omitempty"` // IDs without a stored configuration
+	Experiments []ExperimentAssignment `json:"experiments,omitempty"`
+	Excluded    []MatchExclusion       `json:"excluded,omitempty"`
+	Fallback    bool                   `json:"fallback,omitempty"`
+	Reason      string                 `json:"reason,omitempty"`
+}
+
+// MatchContribution records the points a single datasource key added.
+type MatchContribution struct {
+	SpecificConfig string            `json:"specific_config"`
+	Rule           string            `json:"rule"` // host, url, page or rule
+	Pattern        string            `json:"pattern"`
+	Points         int               `json:"points"`
+	Priority       int               `json:"priority,omitempty"`
+	Broadness      int               `json:"broadness"`
+	Params         map[string]string `json:"params,omitempty"`
+	Query          map[string]string `json:"query,omitempty"`      // query parameters that satisfied the mapping
+	ConfigIDs      []string          `json:"config_ids,omitempty"` // only set for stopped mappings
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIVersion is the version of the resource form written by the server.
+const APIVersion = "ssd/v1"
+
+// Resource kinds.
+const (
+	KindConfig         = "Config"
+	KindSpecificConfig = "SpecificConfig"
+)
+
+// ResourceMeta is the metadata of a configuration or specific config. In the
+// flat form its fields sit next to id; in the resource form they are part of
+// metadata. Labels can be selected on with a label selector; CreatedAt and
+// UpdatedAt are maintained by the server and only written to files in the
+// resource form.
+type ResourceMeta struct {
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	Owner       string            `yaml:"owner,omitempty" json:"owner,omitempty"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	CreatedAt   *time.Time        `yaml:"createdAt,omitempty" json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time        `yaml:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
+
+// WithoutTimestamps returns the metadata without the fields the server
+// maintains, for comparing the content of two revisions.
+func (m ResourceMeta) WithoutTimestamps() ResourceMeta {
+	m.CreatedAt, m.UpdatedAt = nil, nil
+	return m
+}
+
+// ObjectMeta is the metadata block of a resource.
+type ObjectMeta struct {
+	ID           string `yaml:"id" json:"id"`
+	ResourceMeta `yaml:",inline"`
+}
+
+// ConfigResource is a configuration in the resource form:
+//
+//	apiVersion: ssd/v1
+//	kind: Config
+//	metadata:
+//	  id: A
+//	spec:
+//	  actions: [...]
+type ConfigResource struct {
+	APIVersion string     `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string     `yaml:"kind" json:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata" json:"metadata"`
+	Spec       ConfigSpec `yaml:"spec" json:"spec"`
+}
+
+// ConfigSpec is the content of a configuration.
+type ConfigSpec struct {
+	Actions  []Action `yaml:"actions" json:"actions"`
+	Schedule `yaml:",inline"`
+	Rollout  *int `yaml:"rollout,omitempty" json:"rollout,omitempty"`
+}
+
+// SpecificConfigResource is a specific config in the resource form.
+type SpecificConfigResource struct {
+	APIVersion string             `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string             `yaml:"kind" json:"kind"`
+	Metadata   ObjectMeta         `yaml:"metadata" json:"metadata"`
+	Spec       SpecificConfigSpec `yaml:"spec" json:"spec"`
+}
+
+// SpecificConfigSpec is the content of a specific config.
+type SpecificConfigSpec struct {
+	Weights    *Weights   `yaml:"weights,omitempty" json:"weights,omitempty"`
+	DataSource DataSource `yaml:"datasource" json:"datasource"`
+	Rules      []Rule     `yaml:"rules,omitempty" json:"rules,omitempty"`
+	Fallback   *Fallback  `yaml:"fallback,omitempty" json:"fallback,omitempty"`
+	Exclude    *Exclude   `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// NewConfigResource converts a configuration from the flat form.
+func NewConfigResource(config Config) ConfigResource {
+	return ConfigResource{
+		APIVersion: APIVersion,
+		Kind:       KindConfig,
+		Metadata:   ObjectMeta{ID: config.ID, ResourceMeta: config.ResourceMeta},
+		Spec:       ConfigSpec{Actions: config.Actions, Schedule: config.Schedule, Rollout: config.Rollout},
+	}
+}
+
+// Config converts the resource to the flat form. It fails when the resource
+// is of another kind or an unknown version.
+func (r ConfigResource) Config() (Config, error) {
+	if err := checkResource(r.APIVersion, r.Kind, KindConfig); err != nil {
+		return Config{}, err
+	}
+	return Config{
+		ID:           r.Metadata.ID,
+		Actions:      r.Spec.Actions,
+		Schedule:     r.Spec.Schedule,
+		Rollout:      r.Spec.Rollout,
+		ResourceMeta: r.Metadata.ResourceMeta,
+	}, nil
+}
+
+// NewSpecificConfigResource converts a specific config from the flat form.
+func NewSpecificConfigResource(config SpecificConfig) SpecificConfigResource {
+	return SpecificConfigResource{
+		APIVersion: APIVersion,
+		Kind:       KindSpecificConfig,
+		Metadata:   ObjectMeta{ID: config.ID, ResourceMeta: config.ResourceMeta},
+		Spec: SpecificConfigSpec{
+			Weights:    config.Weights,
+			DataSource: config.DataSource,
+			Rules:      config.Rules,
+			Fallback:   config.Fallback,
+			Exclude:    config.Exclude,
+		},
+	}
+}
+
+// SpecificConfig converts the resource to the flat form. It fails when the
+// resource is of another kind or an unknown version.
+func (r SpecificConfigResource) SpecificConfig() (SpecificConfig, error) {
+	if err := checkResource(r.APIVersion, r.Kind, KindSpecificConfig); err != nil {
+		return SpecificConfig{}, err
+	}
+	return SpecificConfig{
+		ID:           r.Metadata.ID,
+		Weights:      r.Spec.Weights,
+		DataSource:   r.Spec.DataSource,
+		Rules:        r.Spec.Rules,
+		Fallback:     r.Spec.Fallback,
+		Exclude:      r.Spec.Exclude,
+		ResourceMeta: r.Metadata.ResourceMeta,
+	}, nil
+}
+
+func checkResource(apiVersion, kind, expected string) error {
+	if apiVersion != APIVersion {
+		return fmt.Errorf("unknown apiVersion '%s', expected %s", apiVersion, APIVersion)
+	}
+	if kind != expected {
+		return fmt.Errorf("kind is '%s', expected %s", kind, expected)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Schedule limits when a configuration or mapping is served. Times are
+// RFC 3339 timestamps or local times ("2006-01-02 15:04", "2006-01-02")
+// interpreted in Timezone, which defaults to UTC. Either bound may be empty.
+type Schedule struct {
+	ActiveFrom  string `yaml:"activeFrom,omitempty" json:"activeFrom,omitempty"`
+	ActiveUntil string `yaml:"activeUntil,omitempty" json:"activeUntil,omitempty"`
+	Timezone    string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
+// IsZero reports whether the schedule is empty. A schedule with only a
+// Timezone is not, so the timezone is still validated.
+func (s Schedule) IsZero() bool {
+	return s.ActiveFrom == "" && s.ActiveUntil == "" && s.Timezone == ""
+}
+
+// ScheduleTransition is an upcoming point in time at which a configuration or
+// mapping becomes active or inactive.
+type ScheduleTransition struct {
+	At             time.Time `json:"at"`
+	Event          string    `json:"event"` // activate or deactivate
+	Kind           string    `json:"kind"`  // config, mapping or rule
+	ConfigID       string    `json:"config_id,omitempty"`
+	SpecificConfig string    `json:"specific_config,omitempty"`
+	Rule           string    `json:"rule,omitempty"` // host, url, page or rule
+	Pattern        string    `json:"pattern,omitempty"`
+	ConfigIDs      []string  `json:"config_ids,omitempty"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "gopkg.in/yaml.v3"
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
 
 type StringSlice []string
 
@@ -23,11 +27,167 @@ func (s *StringSlice) UnmarshalYAML(value *yaml.Node) error {
 
 type SpecificConfig struct {
 	ID         string     `yaml:"id" json:"id"`
+	Weights    *Weights   `yaml:"weights,omitempty" json:"weights,omitempty"`
 	DataSource DataSource `yaml:"datasource" json:"datasource"`
+	Rules      []Rule     `yaml:"rules,omitempty" json:"rules,omitempty"`
+	Fallback   *Fallback  `yaml:"fallback,omitempty" json:"fallback,omitempty"`
+	Exclude    *Exclude   `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+
+	ResourceMeta `yaml:",inline"`
+}
+
+// WithoutTimestamps returns the specific config without the fields the
+// server maintains.
+func (c SpecificConfig) WithoutTimestamps() SpecificConfig {
+	c.ResourceMeta = c.ResourceMeta.WithoutTimestamps()
+	return c
+}
+
+// Exclude vetoes config IDs for matching hosts, urls or pages regardless of
+// their score, e.g. "everywhere except /checkout". Keys take the same
+// patterns as the datasource and a veto applies to every SpecificConfig.
+type Exclude struct {
+	Pages map[string]StringSlice `yaml:"pages,omitempty" json:"pages,omitempty"`
+	URLs  map[string]StringSlice `yaml:"urls,omitempty" json:"urls,omitempty"`
+	Hosts map[string]StringSlice `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+}
+
+// Fallback lists config IDs served when no mapping or rule matches a request.
+// Hosts takes host patterns and wins over the global IDs when the request
+// host matches one of them.
+type Fallback struct {
+	IDs   StringSlice            `yaml:"ids,omitempty" json:"ids,omitempty"`
+	Hosts map[string]StringSlice `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+}
+
+// Weights overrides the points a host, url, page or rule match adds for the
+// mappings of one SpecificConfig. Unset fields keep the defaults (3, 2, 1, 4).
+type Weights struct {
+	Host *int `yaml:"host,omitempty" json:"host,omitempty"`
+	URL  *int `yaml:"url,omitempty" json:"url,omitempty"`
+	Page *int `yaml:"page,omitempty" json:"page,omitempty"`
+	Rule *int `yaml:"rule,omitempty" json:"rule,omitempty"`
+}
+
+// Rule targets config IDs with a compound condition, e.g. "only on page cart
+// of example.com". Rules are evaluated alongside the datasource maps and use
+// the same priority and stop semantics as a Mapping.
+type Rule struct {
+	Name     string      `yaml:"name,omitempty" json:"name,omitempty"`
+	When     Condition   `yaml:"when" json:"when"`
+	IDs      StringSlice `yaml:"ids" json:"ids"`
+	Priority int         `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Stop     bool        `yaml:"stop,omitempty" json:"stop,omitempty"`
+	Schedule `yaml:",inline"`
+}
+
+// Condition is a node of a rule's condition tree. Every field that is set
+// must hold, so a single node is an implicit "all". Host, URL and Page take
+// the same patterns as datasource keys and Query the same values as
+// Mapping.Query. UserAgent is a case-insensitive substring in which "*"
+// matches any characters, or a "re:" regex searched in the header. Headers
+// and Cookies require each name to be present with the given value, or just
+// present when the value is empty.
+type Condition struct {
+	All   []Condition       `yaml:"all,omitempty" json:"all,omitempty"`
+	Any   []Condition       `yaml:"any,omitempty" json:"any,omitempty"`
+	Not   *Condition        `yaml:"not,omitempty" json:"not,omitempty"`
+	Host  string            `yaml:"host,omitempty" json:"host,omitempty"`
+	URL   string            `yaml:"url,omitempty" json:"url,omitempty"`
+	Page  string            `yaml:"page,omitempty" json:"page,omitempty"`
+	Query map[string]string `yaml:"query,omitempty" json:"query,omitempty"`
+
+	Device    string            `yaml:"device,omitempty" json:"device,omitempty"`       // mobile, tablet, desktop, tv or bot
+	Language  string            `yaml:"language,omitempty" json:"language,omitempty"`   // accepted language, "tr" also matches "tr-TR"
+	UserAgent string            `yaml:"userAgent,omitempty" json:"userAgent,omitempty"` // pattern on the raw User-Agent
+	Headers   map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Cookies   map[string]string `yaml:"cookies,omitempty" json:"cookies,omitempty"`
 }
 
 type DataSource struct {
-	Pages map[string]StringSlice `yaml:"pages" json:"pages"`
-	URLs  map[string]StringSlice `yaml:"urls" json:"urls"`
-	Hosts map[string]StringSlice `yaml:"hosts" json:"hosts"`
+	Pages map[string]Mapping `yaml:"pages" json:"pages"`
+	URLs  map[string]Mapping `yaml:"urls" json:"urls"`
+	Hosts map[string]Mapping `yaml:"hosts" json:"hosts"`
+}
+
+// Mapping lists the config IDs of a datasource key. It is written either as
+// a plain list (or single string) of IDs or, when more is needed, as
+//
+//	ids: [A.yaml]
+//	priority: 10
+//	stop: true
+//	query:
+//	  utm_campaign: spring
+//	activeFrom: "2026-03-01 09:00"
+//	activeUntil: "2026-03-31"
+//	timezone: Europe/Istanbul
+//
+// Mappings with a higher priority rank first regardless of score; a matched
+// mapping with stop set discards every matched mapping of lower priority.
+// A mapping with activeFrom or activeUntil is only served inside that window.
+// Query restricts the mapping to requests carrying the given parameters; a
+// value is matched exactly, "prefix:x" matches the start, "re:x" an anchored
+// regex and "" or "*" only requires the parameter to be present.
+type Mapping struct {
+	IDs      StringSlice       `yaml:"ids" json:"ids"`
+	Priority int               `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Stop     bool              `yaml:"stop,omitempty" json:"stop,omitempty"`
+	Query    map[string]string `yaml:"query,omitempty" json:"query,omitempty"`
+	Schedule `yaml:",inline"`
+}
+
+// mappingFields avoids recursing into Mapping's own (un)marshalers.
+type mappingFields Mapping
+
+// isPlain reports whether the mapping can be written as a bare ID list.
+func (m Mapping) isPlain() bool {
+	return m.Priority == 0 && !m.Stop && len(m.Query) == 0 && m.Schedule.IsZero()
+}
+
+func (m *Mapping) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return value.Decode(&m.IDs)
+	}
+
+	var fields mappingFields
+	if err := value.Decode(&fields); err != nil {
+		return err
+	}
+	*m = Mapping(fields)
+	return nil
+}
+
+func (m Mapping) MarshalYAML() (interface{}, error) {
+	if m.isPlain() {
+		return []string(m.IDs), nil
+	}
+	return mappingFields(m), nil
+}
+
+func (m *Mapping) UnmarshalJSON(data []byte) error {
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err == nil {
+		m.IDs = ids
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		m.IDs = []string{single}
+		return nil
+	}
+
+	var fields mappingFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*m = Mapping(fields)
+	return nil
+}
+
+func (m Mapping) MarshalJSON() ([]byte, error) {
+	if m.isPlain() {
+		return json.Marshal([]string(m.IDs))
+	}
+	return json.Marshal(mappingFields(m))
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Configuration states. Only published configurations are served.
+const (
+	StateDraft     = "draft"
+	StateInReview  = "in_review"
+	StatePublished = "published"
+	StateArchived  = "archived"
+)
+
+// ConfigDraft is a work-in-progress revision of a configuration.
+type ConfigDraft struct {
+	Config      Config    `yaml:"config" json:"config"`
+	State       string    `yaml:"state" json:"state"` // draft or in_review
+	UpdatedBy   string    `yaml:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+	UpdatedAt   time.Time `yaml:"updatedAt" json:"updatedAt"`
+	SubmittedBy string    `yaml:"submittedBy,omitempty" json:"submittedBy,omitempty"`
+}
+
+// ConfigStatus summarizes where a configuration is in the workflow. State is
+// the state of the draft when there is one, published or archived otherwise.
+type ConfigStatus struct {
+	ID        string `json:"id"`
+	State     string `json:"state"`
+	Published bool   `json:"published"`
+	HasDraft  bool   `json:"has_draft"`
+}
+
+// ConfigDiff compares the draft of a configuration with its published
+// revision as a line diff of their YAML; lines start with "+", "-" or " ".
+type ConfigDiff struct {
+	ID        string   `json:"id"`
+	Published *Config  `json:"published,omitempty"`
+	Draft     *Config  `json:"draft,omitempty"`
+	Changed   bool     `json:"changed"`
+	Lines     []string `json:"lines"`
+}
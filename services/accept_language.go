@@ -0,0 +1,68 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header
+// lowercased and ordered by preference. Tags with q=0 are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag, quality})
+	}
+
+	// Stable so equal qualities keep the order the client sent them in
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	languages := make([]string, len(tags))
+	for i, t := range tags {
+		languages[i] = t.tag
+	}
+	return languages
+}
+
+// matchLanguage reports whether tag equals want or is one of its regional
+// variants, so "tr" matches "tr" and "tr-tr".
+func matchLanguage(tag, want string) bool {
+	want = strings.ToLower(want)
+	return tag == want || strings.HasPrefix(tag, want+"-")
+}
+
+// acceptsLanguage reports whether any language the visitor accepts, at any
+// quality above zero, matches want.
+func acceptsLanguage(languages []string, want string) bool {
+	for _, tag := range languages {
+		if matchLanguage(tag, want) {
+			return true
+		}
+	}
+	return false
+}
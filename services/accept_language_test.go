@@ -0,0 +1,22 @@
+package services
+
+import "testing"
+
+func TestAcceptsLanguage(t *testing.T) {
+	languages := ParseAcceptLanguage("en-US,en;q=0.9,tr-TR;q=0.8,de;q=0")
+	cases := []struct {
+		want string
+		ok   bool
+	}{
+		{"en", true},
+		{"tr", true},
+		{"TR", true},
+		{"de", false}, // q=0 means not acceptable
+		{"fr", false},
+	}
+	for _, c := range cases {
+		if got := acceptsLanguage(languages, c.want); got != c.ok {
+			t.Errorf("%v accepts %s: %v, want %v", languages, c.want, got, c.ok)
+		}
+	}
+}
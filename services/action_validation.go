@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"ssd-assignment-api/models"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FieldError is a validation problem together with the path of the field it
+// was found in, e.g. ["actions", "1", "selector"]. Rule names the check that
+// failed: schema, selector or html.
+type FieldError struct {
+	Path    []string
+	Rule    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return FieldName(e.Path) + ": " + e.Message
+}
+
+// FieldName formats a field path, writing numeric segments as indexes:
+// ["actions", "1", "selector"] becomes "actions[1].selector".
+func FieldName(path []string) string {
+	var b strings.Builder
+	for _, segment := range path {
+		if _, err := strconv.Atoi(segment); err == nil {
+			b.WriteString("[" + segment + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+// Action types and insert positions a configuration may use.
+var (
+	actionTypes     = []string{"remove", "replace", "insert", "alter"}
+	insertPositions = []string{"before", "after", "beforebegin", "afterbegin", "beforeend", "afterend"}
+)
+
+// validateActions checks every action against the schema of its type and
+// the selector and HTML policies.
+func validateActions(actions []models.Action) []FieldError {
+	var problems []FieldError
+	for i, action := range actions {
+		at := func(field string) []string { return []string{"actions", strconv.Itoa(i), field} }
+		require := func(field, value string) {
+			if strings.TrimSpace(value) == "" {
+				problems = append(problems, FieldError{at(field), "schema", fmt.Sprintf("%s is required for %s actions", field, action.Type)})
+			}
+		}
+
+		switch action.Type {
+		case "remove":
+			require("selector", action.Selector)
+		case "replace":
+			require("selector", action.Selector)
+			require("newElement", action.NewElement)
+		case "insert":
+			require("target", action.Target)
+			require("newElement", action.NewElement)
+			if !containsString(insertPositions, action.Position) {
+				problems = append(problems, FieldError{at("position"), "schema",
+					fmt.Sprintf("position must be one of %s", strings.Join(insertPositions, ", "))})
+			}
+		case "alter":
+			require("oldValue", action.OldValue)
+		case "":
+			problems = append(problems, FieldError{at("type"), "schema", "type is required"})
+		default:
+			problems = append(problems, FieldError{at("type"), "schema",
+				fmt.Sprintf("unknown action type '%s', expected one of %s", action.Type, strings.Join(actionTypes, ", "))})
+		}
+
+		for _, field := range []struct{ name, selector string }{{"selector", action.Selector}, {"target", action.Target}} {
+			if field.selector == "" {
+				continue
+			}
+			if err := validateSelector(field.selector); err != nil {
+				problems = append(problems, FieldError{at(field.name), "selector", err.Error()})
+			}
+		}
+		if action.NewElement != "" {
+			if err := validateHTML(action.NewElement); err != nil {
+				problems = append(problems, FieldError{at("newElement"), "html", err.Error()})
+			}
+		}
+	}
+	return problems
+}
+
+// validateSelector checks that a CSS selector is well formed: brackets,
+// parentheses and quotes are balanced, no group is empty and it does not
+// end with a combinator. Characters that can only be injection attempts,
+// such as braces or a semicolon, are rejected.
+func validateSelector(selector string) error {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return errors.New("selector is empty")
+	}
+
+	var stack []rune
+	var quote rune
+	for _, r := range selector {
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '"', '\'':
+			quote = r
+		case '[', '(':
+			stack = append(stack, r)
+		case ']', ')':
+			open := map[rune]rune{']': '[', ')': '('}[r]
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return fmt.Errorf("selector '%s' has an unmatched '%c'", selector, r)
+			}
+			stack = stack[:len(stack)-1]
+		case '{', '}', ';', '<':
+			return fmt.Errorf("selector '%s' must not contain '%c'", selector, r)
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("selector '%s' has an unterminated string", selector)
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("selector '%s' has an unclosed '%c'", selector, stack[len(stack)-1])
+	}
+
+	for _, group := range strings.Split(selector, ",") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			return fmt.Errorf("selector '%s' has an empty group", selector)
+		}
+		if strings.ContainsAny(group[len(group)-1:], ">+~") {
+			return fmt.Errorf("selector '%s' ends with a combinator", selector)
+		}
+	}
+	return nil
+}
+
+// HTML policy: elements that run or load code and attributes that can carry
+// script are not allowed in newElement.
+var (
+	forbiddenElements = []string{"script", "iframe", "frame", "frameset", "object", "embed", "base", "meta", "link"}
+	urlAttributes     = []string{"href", "src", "action", "formaction", "xlink:href"}
+	voidElements      = []string{"area", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr"}
+)
+
+// validateHTML checks a newElement fragment against the HTML policy and
+// reports tags that are not closed or closed out of order.
+func validateHTML(fragment string) error {
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	var open []string
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return err
+			}
+			if len(open) > 0 {
+				return fmt.Errorf("<%s> is not closed", open[len(open)-1])
+			}
+			return nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if containsString(forbiddenElements, token.Data) {
+				return fmt.Errorf("<%s> elements are not allowed", token.Data)
+			}
+			for _, attr := range token.Attr {
+				key := strings.ToLower(attr.Key)
+				if strings.HasPrefix(key, "on") {
+					return fmt.Errorf("event handler attribute '%s' is not allowed", attr.Key)
+				}
+				value := strings.ToLower(strings.TrimSpace(attr.Val))
+				if containsString(urlAttributes, key) && (strings.HasPrefix(value, "javascript:") || strings.HasPrefix(value, "vbscript:")) {
+					return fmt.Errorf("script URL in attribute '%s' is not allowed", attr.Key)
+				}
+			}
+			if tokenType == html.StartTagToken && !containsString(voidElements, token.Data) {
+				open = append(open, token.Data)
+			}
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if len(open) == 0 || open[len(open)-1] != token.Data {
+				return fmt.Errorf("unexpected </%s>", token.Data)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+}
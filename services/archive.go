@@ -0,0 +1,370 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+	"time"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Archive formats and import modes.
+const (
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+
+	ImportSkip      = "skip"      // keep existing documents
+	ImportOverwrite = "overwrite" // replace existing documents
+	ImportFail      = "fail"      // refuse the import if any document exists
+)
+
+const manifestPath = "manifest.yaml"
+
+// Limits on the decompressed contents of an imported archive, so a small
+// upload can not expand into more than the server wants to hold.
+const (
+	maxArchiveEntrySize = 4 << 20
+	maxArchiveSize      = 64 << 20
+)
+
+// ArchiveService exports every configuration and specific configuration as
+// an archive and imports such archives all or nothing.
+type ArchiveService struct {
+	configs   *ConfigService
+	specifics *SpecificConfigService
+	audit     *AuditLog
+}
+
+func NewArchiveService(configs *ConfigService, specifics *SpecificConfigService, audit *AuditLog) *ArchiveService {
+	return &ArchiveService{configs: configs, specifics: specifics, audit: audit}
+}
+
+// archiveFile is a document inside an archive.
+type archiveFile struct {
+	entry models.ArchiveEntry
+	data  []byte
+}
+
+// Export writes all documents, ordered by ID, and a manifest to w.
+func (s *ArchiveService) Export(w io.Writer, format string) error {
+	var files []archiveFile
+	configs, _ := s.configs.GetAllConfigs()
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ID < configs[j].ID })
+	for _, config := range configs {
+		files = append(files, newArchiveFile(models.ChangeKindConfig, config.ID, []byte(configYAML(config, true))))
+	}
+	specifics, _ := s.specifics.GetAllSpecificConfigs()
+	sort.Slice(specifics, func(i, j int) bool { return specifics[i].ID < specifics[j].ID })
+	for _, config := range specifics {
+		files = append(files, newArchiveFile(models.ChangeKindSpecific, config.ID, []byte(specificYAML(config, true))))
+	}
+
+	manifest := models.ArchiveManifest{Version: 1, ExportedAt: time.Now().UTC()}
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, file.entry)
+	}
+	var manifestData bytes.Buffer
+	encoder := yaml.NewEncoder(&manifestData)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	encoder.Close()
+	files = append([]archiveFile{{entry: models.ArchiveEntry{Path: manifestPath}, data: manifestData.Bytes()}}, files...)
+
+	switch format {
+	case ArchiveTarGz:
+		return writeTarGz(w, files, manifest.ExportedAt)
+	case ArchiveZip:
+		return writeZip(w, files, manifest.ExportedAt)
+	}
+	return fmt.Errorf("unknown archive format '%s'", format)
+}
+
+func newArchiveFile(kind, id string, data []byte) archiveFile {
+	dir := "configs"
+	if kind == models.ChangeKindSpecific {
+		dir = "specific_configs"
+	}
+	sum := sha256.Sum256(data)
+	return archiveFile{
+		entry: models.ArchiveEntry{Path: path.Join(dir, id+".yaml"), Kind: kind, ID: id, SHA256: hex.EncodeToString(sum[:])},
+		data:  data,
+	}
+}
+
+func writeTarGz(w io.Writer, files []archiveFile, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		header := &tar.Header{Name: file.entry.Path, Mode: 0644, Size: int64(len(file.data)), ModTime: modTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(w io.Writer, files []archiveFile, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: file.entry.Path, Method: zip.Deflate, Modified: modTime})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadArchive checks every document of a tar.gz or zip archive against its
+// manifest, parses and validates it, and returns the documents as an import
+// of the given mode.
+func (s *ArchiveService) ReadArchive(data []byte, mode string) (models.ImportProposal, error) {
+	if err := checkImportMode(mode); err != nil {
+		return models.ImportProposal{}, err
+	}
+	contents, err := readArchive(data)
+	if err != nil {
+		return models.ImportProposal{}, err
+	}
+
+	manifestData, ok := contents[manifestPath]
+	if !ok {
+		return models.ImportProposal{}, errors.New("archive has no manifest.yaml")
+	}
+	var manifest models.ArchiveManifest
+	if err := yaml.Unmarshal(manifestData, &manifest); err != nil {
+		return models.ImportProposal{}, fmt.Errorf("manifest.yaml could not be parsed: %w", err)
+	}
+
+	proposal := models.ImportProposal{Mode: mode}
+	for _, entry := range manifest.Files {
+		data, ok := contents[entry.Path]
+		if !ok {
+			return models.ImportProposal{}, fmt.Errorf("%s is listed in the manifest but missing", entry.Path)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return models.ImportProposal{}, fmt.Errorf("%s does not match its checksum", entry.Path)
+		}
+
+		switch entry.Kind {
+		case models.ChangeKindConfig:
+			var config models.Config
+			if err := yamlv2.Unmarshal(data, &config); err != nil {
+				return models.ImportProposal{}, fmt.Errorf("%s could not be parsed: %w", entry.Path, err)
+			}
+			if config.ID != entry.ID {
+				return models.ImportProposal{}, fmt.Errorf("%s has ID '%s', the manifest says '%s'", entry.Path, config.ID, entry.ID)
+			}
+			proposal.Configs = append(proposal.Configs, config)
+		case models.ChangeKindSpecific:
+			var config models.SpecificConfig
+			if err := yaml.Unmarshal(data, &config); err != nil {
+				return models.ImportProposal{}, fmt.Errorf("%s could not be parsed: %w", entry.Path, err)
+			}
+			if config.ID != entry.ID {
+				return models.ImportProposal{}, fmt.Errorf("%s has ID '%s', the manifest says '%s'", entry.Path, config.ID, entry.ID)
+			}
+			proposal.SpecificConfigs = append(proposal.SpecificConfigs, config)
+		default:
+			return models.ImportProposal{}, fmt.Errorf("%s has unknown kind '%s'", entry.Path, entry.Kind)
+		}
+	}
+
+	if _, err := s.planImport(proposal); err != nil {
+		return models.ImportProposal{}, err
+	}
+	return proposal, nil
+}
+
+func checkImportMode(mode string) error {
+	switch mode {
+	case ImportSkip, ImportOverwrite, ImportFail:
+		return nil
+	}
+	return fmt.Errorf("unknown import mode '%s'", mode)
+}
+
+// Import validates every document against the services before changing
+// anything, then applies them. If applying fails halfway, the documents
+// applied so far are rolled back. With dryRun only the result is computed.
+func (s *ArchiveService) Import(user string, proposal models.ImportProposal, dryRun bool) (models.ImportResult, error) {
+	mode := proposal.Mode
+	result := models.ImportResult{Mode: mode, DryRun: dryRun, Created: []string{}, Updated: []string{}, Skipped: []string{}}
+	if err := checkImportMode(mode); err != nil {
+		return result, err
+	}
+	plan, err := s.planImport(proposal)
+	if err != nil {
+		return result, err
+	}
+
+	var apply []documentStep
+	for _, step := range plan {
+		ref := step.kind + ":" + step.id
+		switch {
+		case !step.exists:
+			result.Created = append(result.Created, ref)
+		case mode == ImportFail:
+			return result, fmt.Errorf("%s already exists", ref)
+		case mode == ImportSkip:
+			result.Skipped = append(result.Skipped, ref)
+			continue
+		default:
+			result.Updated = append(result.Updated, ref)
+		}
+		apply = append(apply, step)
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	for i, step := range apply {
+		if err := step.apply(s.configs, s.specifics); err != nil {
+			err = fmt.Errorf("%s:%s could not be imported: %w", step.kind, step.id, err)
+			var failed []string
+			for j := i - 1; j >= 0; j-- {
+				if rollbackErr := apply[j].rollback(s.configs, s.specifics); rollbackErr != nil {
+					failed = append(failed, fmt.Sprintf("%s:%s: %v", apply[j].kind, apply[j].id, rollbackErr))
+				}
+			}
+			if len(failed) > 0 {
+				return result, fmt.Errorf("%w; these documents could not be restored: %s", err, strings.Join(failed, "; "))
+			}
+			return result, fmt.Errorf("%w; nothing was changed", err)
+		}
+	}
+
+	if s.audit != nil {
+		return result, s.audit.Record(user, "archive.import", "", fmt.Sprintf("mode %s: %d created, %d updated, %d skipped",
+			mode, len(result.Created), len(result.Updated), len(result.Skipped)))
+	}
+	return result, nil
+}
+
+// planImport validates every document and looks up the revision it would
+// replace.
+func (s *ArchiveService) planImport(proposal models.ImportProposal) ([]documentStep, error) {
+	seen := make(map[string]bool)
+	var plan []documentStep
+	for _, config := range proposal.Configs {
+		ref := models.ChangeKindConfig + ":" + config.ID
+		if err := checkFileName(config.ID); err != nil {
+			return nil, err
+		}
+		if seen[ref] {
+			return nil, fmt.Errorf("%s is in the archive twice", ref)
+		}
+		seen[ref] = true
+		if err := validateConfig(config); err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+		previous, err := s.configs.GetConfigByID(config.ID)
+		plan = append(plan, documentStep{kind: models.ChangeKindConfig, id: config.ID, config: config, exists: err == nil, previousConfig: previous})
+	}
+	for _, config := range proposal.SpecificConfigs {
+		ref := models.ChangeKindSpecific + ":" + config.ID
+		if err := checkFileName(config.ID); err != nil {
+			return nil, err
+		}
+		if seen[ref] {
+			return nil, fmt.Errorf("%s is in the archive twice", ref)
+		}
+		seen[ref] = true
+		if err := s.specifics.ValidateSpecificConfig(config); err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+		previous, err := s.specifics.GetSpecificConfigByID(config.ID)
+		plan = append(plan, documentStep{kind: models.ChangeKindSpecific, id: config.ID, specific: config, exists: err == nil, previousSpecific: previous})
+	}
+	return plan, nil
+}
+
+// readArchive returns the regular files of a tar.gz or zip archive by path.
+// It stops at the first file larger than maxArchiveEntrySize or once the
+// files add up to more than maxArchiveSize.
+func readArchive(data []byte) (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+	var total int64
+	read := func(name string, r io.Reader) error {
+		data, err := io.ReadAll(io.LimitReader(r, maxArchiveEntrySize+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > maxArchiveEntrySize {
+			return fmt.Errorf("%s is larger than %d bytes", name, maxArchiveEntrySize)
+		}
+		if total += int64(len(data)); total > maxArchiveSize {
+			return fmt.Errorf("archive holds more than %d bytes", maxArchiveSize)
+		}
+		contents[path.Clean(name)] = data
+		return nil
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip archive: %w", err)
+		}
+		tr := tar.NewReader(gz)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid tar archive: %w", err)
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := read(header.Name, tr); err != nil {
+				return nil, err
+			}
+		}
+	case bytes.HasPrefix(data, []byte("PK")):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+		for _, file := range zr.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = read(file.Name, rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.New("archive must be a tar.gz or zip file")
+	}
+	return contents, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"ssd-assignment-api/models"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func newTestArchiveService(t *testing.T) *ArchiveService {
+	t.Helper()
+	dir := t.TempDir()
+	for _, sub := range []string{"/configs", "/specific_configs"} {
+		if err := os.Mkdir(dir+sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configs, err := NewConfigService(dir+"/configs", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	specifics, err := NewSpecificConfigService(dir+"/specific_configs", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewArchiveService(configs, specifics, nil)
+}
+
+// testArchive builds an archive of the files with a manifest listing them.
+func testArchive(t *testing.T, format string, files ...archiveFile) []byte {
+	t.Helper()
+	manifest := models.ArchiveManifest{Version: 1}
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, file.entry)
+	}
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append([]archiveFile{{entry: models.ArchiveEntry{Path: manifestPath}, data: data}}, files...)
+
+	var buf bytes.Buffer
+	write := writeTarGz
+	if format == ArchiveZip {
+		write = writeZip
+	}
+	if err := write(&buf, files, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadArchive(t *testing.T) {
+	config := newArchiveFile(models.ChangeKindConfig, "A", []byte("id: A\nactions: []\n"))
+	traversal := newArchiveFile(models.ChangeKindConfig, "../A", []byte("id: ../A\nactions: []\n"))
+	hidden := newArchiveFile(models.ChangeKindSpecific, ".s", []byte("id: .s\ndatasource: {}\n"))
+	huge := newArchiveFile(models.ChangeKindConfig, "B", bytes.Repeat([]byte(" "), maxArchiveEntrySize+1))
+
+	// Many entries below the per-file limit that add up to more than the total
+	var bulk []archiveFile
+	for i := 0; i*maxArchiveEntrySize <= maxArchiveSize; i++ {
+		file := newArchiveFile(models.ChangeKindConfig, "A", bytes.Repeat([]byte(" "), maxArchiveEntrySize))
+		file.entry.Path = strings.Repeat("x", i+1)
+		bulk = append(bulk, file)
+	}
+
+	cases := []struct {
+		name  string
+		files []archiveFile
+		err   string
+	}{
+		{"valid", []archiveFile{config}, ""},
+		{"id outside the directory", []archiveFile{traversal}, "can not be used as a file name"},
+		{"hidden id", []archiveFile{hidden}, "can not be used as a file name"},
+		{"entry too large", []archiveFile{huge}, "is larger than"},
+		{"archive too large", bulk, "archive holds more than"},
+	}
+	service := newTestArchiveService(t)
+	for _, format := range []string{ArchiveTarGz, ArchiveZip} {
+		for _, c := range cases {
+			t.Run(format+"/"+c.name, func(t *testing.T) {
+				_, err := service.ReadArchive(testArchive(t, format, c.files...), ImportFail)
+				switch {
+				case c.err == "" && err != nil:
+					t.Fatal(err)
+				case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+					t.Fatalf("got error %v, want %q", err, c.err)
+				}
+			})
+		}
+	}
+}
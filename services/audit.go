@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"ssd-assignment-api/models"
+	"sync"
+	"time"
+)
+
+// AuditLog keeps every audited change in memory and appends it as a JSON line
+// to a file, so the trail survives restarts.
+type AuditLog struct {
+	entries []models.AuditEntry
+	mutex   sync.Mutex
+	path    string
+}
+
+func NewAuditLog(path string) (*AuditLog, error) {
+	log := &AuditLog{path: path}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry models.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("audit log line %d: %w", i+1, err)
+		}
+		log.entries = append(log.entries, entry)
+	}
+
+	return log, nil
+}
+
+// Record appends an entry stamped with the current time.
+func (l *AuditLog) Record(user, action, target, detail string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry := models.AuditEntry{At: time.Now().UTC(), User: user, Action: action, Target: target, Detail: detail}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries returns the audit trail, newest first.
+func (l *AuditLog) Entries() []models.AuditEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := make([]models.AuditEntry, len(l.entries))
+	for i, entry := range l.entries {
+		entries[len(l.entries)-1-i] = entry
+	}
+	return entries
+}
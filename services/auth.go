@@ -14,7 +14,8 @@ var secretKey = []byte("your_secret_key") // Replace with a more secure key
 
 // Claims struct for JWT token
 type Claims struct {
-	Username string `json:"username"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -23,6 +24,7 @@ func GenerateToken(username string) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour) // Token expires in 24 hours
 	claims := &Claims{
 		Username: username,
+		Roles:    RolesFor(username),
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expirationTime.Unix(),
 			Issuer:    "your-app",
@@ -76,6 +78,20 @@ func TokenAuthMiddleware() gin.HandlerFunc {
 
 		// Store the username in the context for later use
 		c.Set("username", claims.Username)
+		c.Set("roles", claims.Roles)
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose token does not carry the role. It must
+// run after TokenAuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRole(c.GetStringSlice("roles"), role) {
+			c.JSON(403, gin.H{"message": fmt.Sprintf("The %s role is required", role)})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeRequestService holds proposed changes until a second person reviews
+// them. Approval is the only way a proposal changes what is served.
+type ChangeRequestService struct {
+	requests map[string]models.ChangeRequest
+	ChangeTargets
+	audit   *AuditLog
+	mutex   sync.Mutex
+	yamlDir string
+}
+
+// ChangeTargets are the services approved changes are applied to.
+type ChangeTargets struct {
+	Configs      *ConfigService
+	Specifics    *SpecificConfigService
+	Experiments  *ExperimentService
+	Archives     *ArchiveService
+	Environments *EnvironmentService
+}
+
+func NewChangeRequestService(yamlDir string, targets ChangeTargets, audit *AuditLog) (*ChangeRequestService, error) {
+	service := &ChangeRequestService{
+		requests:      make(map[string]models.ChangeRequest),
+		ChangeTargets: targets,
+		audit:         audit,
+		yamlDir:       yamlDir,
+	}
+
+	if err := os.MkdirAll(yamlDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	files, err := os.ReadDir(yamlDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
+			continue
+		}
+
+		filePath := filepath.Join(yamlDir, file.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+		var request models.ChangeRequest
+		if err := yaml.Unmarshal(data, &request); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+		service.requests[request.ID] = request
+	}
+
+	return service, nil
+}
+
+// Propose records a pending change after checking it could be applied.
+// Changes that need the publisher role on their own routes need it here too.
+func (s *ChangeRequestService) Propose(user string, roles []string, proposal models.ChangeProposal) (models.ChangeRequest, error) {
+	if needsPublisher(proposal) && !HasRole(roles, RolePublisher) {
+		return models.ChangeRequest{}, fmt.Errorf("the %s role is required to propose a %s %s", RolePublisher, proposal.Operation, proposal.Kind)
+	}
+	if err := s.validate(&proposal); err != nil {
+		return models.ChangeRequest{}, err
+	}
+
+	id, err := newChangeRequestID()
+	if err != nil {
+		return models.ChangeRequest{}, err
+	}
+	request := models.ChangeRequest{
+		ID:             id,
+		ChangeProposal: proposal,
+		Status:         models.ChangePending,
+		ProposedBy:     user,
+		ProposedAt:     time.Now().UTC(),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.save(request); err != nil {
+		return models.ChangeRequest{}, err
+	}
+	s.requests[id] = request
+	return request, s.audit.Record(user, "change.propose", request.ID, describeChange(proposal))
+}
+
+// needsPublisher reports whether a change is one only publishers may make:
+// anything but creating, updating or deleting a specific config and deleting
+// a config.
+func needsPublisher(proposal models.ChangeProposal) bool {
+	switch proposal.Kind {
+	case models.ChangeKindConfig:
+		return proposal.Operation != models.ChangeDelete
+	case models.ChangeKindSpecific:
+		return false
+	}
+	return true
+}
+
+// validate checks the proposal is complete and fits the current state, and
+// fills TargetID from the payload when it is missing.
+func (s *ChangeRequestService) validate(proposal *models.ChangeProposal) error {
+	switch proposal.Kind {
+	case models.ChangeKindConfig, models.ChangeKindSpecific, models.ChangeKindExperiment:
+		return s.validateDocument(proposal)
+	case models.ChangeKindRollout, models.ChangeKindImport, models.ChangeKindPromotion:
+		if proposal.Operation != models.ChangeApply {
+			return fmt.Errorf("the operation of a %s change must be %s", proposal.Kind, models.ChangeApply)
+		}
+		return s.validatePayload(proposal)
+	}
+	return fmt.Errorf("unknown change kind '%s'", proposal.Kind)
+}
+
+// validateDocument checks a change to a config, specific config or
+// experiment.
+func (s *ChangeRequestService) validateDocument(proposal *models.ChangeProposal) error {
+	var exists bool
+	withoutDocument := proposal.Operation == models.ChangeDelete || proposal.Operation == models.ChangeArchive
+	switch proposal.Kind {
+	case models.ChangeKindConfig:
+		// Configs are created and updated as drafts, which an approver
+		// publishes, so a change request can only delete or archive one
+		if !withoutDocument {
+			return errors.New("configs are created and updated through drafts, not change requests")
+		}
+		_, err := s.Configs.GetConfigByID(proposal.TargetID)
+		exists = err == nil
+	case models.ChangeKindSpecific:
+		if proposal.SpecificConfig != nil && proposal.TargetID == "" {
+			proposal.TargetID = proposal.SpecificConfig.ID
+		}
+		_, err := s.Specifics.GetSpecificConfigByID(proposal.TargetID)
+		exists = err == nil
+		if !withoutDocument && proposal.SpecificConfig == nil {
+			return errors.New("specific config is required")
+		}
+		if proposal.SpecificConfig != nil {
+			proposal.SpecificConfig.ID = proposal.TargetID
+			if err := s.Specifics.ValidateSpecificConfig(*proposal.SpecificConfig); err != nil {
+				return err
+			}
+		}
+	case models.ChangeKindExperiment:
+		if proposal.Experiment != nil && proposal.TargetID == "" {
+			proposal.TargetID = proposal.Experiment.ID
+		}
+		_, err := s.Experiments.GetExperimentByID(proposal.TargetID)
+		exists = err == nil
+		if !withoutDocument && proposal.Experiment == nil {
+			return errors.New("experiment is required")
+		}
+		if proposal.Experiment != nil {
+			proposal.Experiment.ID = proposal.TargetID
+			if err := validateExperiment(*proposal.Experiment); err != nil {
+				return err
+			}
+		}
+	}
+
+	if proposal.TargetID == "" {
+		return errors.New("target ID is required")
+	}
+	switch proposal.Operation {
+	case models.ChangeCreate:
+		if exists {
+			return fmt.Errorf("'%s' already exists", proposal.TargetID)
+		}
+		if err := checkFileName(proposal.TargetID); err != nil {
+			return err
+		}
+	case models.ChangeUpdate, models.ChangeDelete:
+		if !exists {
+			return fmt.Errorf("'%s' not found", proposal.TargetID)
+		}
+	case models.ChangeArchive:
+		if proposal.Kind != models.ChangeKindConfig {
+			return errors.New("only configs can be archived")
+		}
+		if !exists {
+			return fmt.Errorf("'%s' not found", proposal.TargetID)
+		}
+	default:
+		return fmt.Errorf("unknown operation '%s'", proposal.Operation)
+	}
+	return nil
+}
+
+// validatePayload checks a rollout, import or promotion by
+// dry-running it where the target service supports that.
+func (s *ChangeRequestService) validatePayload(proposal *models.ChangeProposal) error {
+	switch proposal.Kind {
+	case models.ChangeKindRollout:
+		if proposal.Rollout == nil {
+			return errors.New("rollout percentage is required")
+		}
+		if _, err := s.Configs.GetConfigByID(proposal.TargetID); err != nil {
+			return fmt.Errorf("'%s' not found", proposal.TargetID)
+		}
+		return validateRollout(proposal.Rollout)
+	case models.ChangeKindImport:
+		if proposal.Import == nil {
+			return errors.New("import is required")
+		}
+		if _, err := s.Archives.Import("", *proposal.Import, true); err != nil {
+			return err
+		}
+		proposal.TargetID = "archive"
+	case models.ChangeKindPromotion:
+		if proposal.Promotion == nil {
+			return errors.New("promotion is required")
+		}
+		promotion := *proposal.Promotion
+		promotion.DryRun = true
+		result, err := s.Environments.Promote("", promotion)
+		if err != nil {
+			return err
+		}
+		proposal.Promotion.To = result.To
+		proposal.Promotion.DryRun = false
+		proposal.TargetID = result.From
+	}
+	return nil
+}
+
+// Approve applies a pending change. The reviewer must be an approver other
+// than the proposer. The approval is saved before the change is applied, so
+// a request whose status could not be saved is never applied twice; when
+// applying fails the request is pending again.
+func (s *ChangeRequestService) Approve(user, id, comment string) (models.ChangeRequest, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, err := s.reviewable(user, id)
+	if err != nil {
+		return models.ChangeRequest{}, err
+	}
+	approved := reviewed(user, request, models.ChangeApproved, comment)
+	if err := s.save(approved); err != nil {
+		return models.ChangeRequest{}, err
+	}
+	if err := s.apply(request.ProposedBy, request.ChangeProposal); err != nil {
+		if err := s.save(request); err != nil {
+			return models.ChangeRequest{}, fmt.Errorf("change could not be applied and the request could not be reset to pending: %w", err)
+		}
+		return models.ChangeRequest{}, fmt.Errorf("change could not be applied: %w", err)
+	}
+
+	s.requests[id] = approved
+	return approved, s.audit.Record(user, "change."+models.ChangeApproved, id, comment)
+}
+
+// Reject closes a pending change without applying it.
+func (s *ChangeRequestService) Reject(user, id, comment string) (models.ChangeRequest, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, err := s.reviewable(user, id)
+	if err != nil {
+		return models.ChangeRequest{}, err
+	}
+	return s.review(user, request, models.ChangeRejected, comment)
+}
+
+// reviewable returns a pending request the user may review: the user must
+// be an approver and not the proposer. Callers must hold the mutex.
+func (s *ChangeRequestService) reviewable(user, id string) (models.ChangeRequest, error) {
+	if !HasRole(RolesFor(user), RoleApprover) {
+		return models.ChangeRequest{}, errors.New("only approvers can review change requests")
+	}
+	request, exists := s.requests[id]
+	if !exists {
+		return request, errors.New("change request not found")
+	}
+	if request.Status != models.ChangePending {
+		return request, fmt.Errorf("change request is already %s", request.Status)
+	}
+	if request.ProposedBy == user {
+		return request, errors.New("a change request can not be reviewed by its proposer")
+	}
+	return request, nil
+}
+
+func (s *ChangeRequestService) review(user string, request models.ChangeRequest, status, comment string) (models.ChangeRequest, error) {
+	request = reviewed(user, request, status, comment)
+	if err := s.save(request); err != nil {
+		return models.ChangeRequest{}, err
+	}
+	s.requests[request.ID] = request
+	return request, s.audit.Record(user, "change."+status, request.ID, comment)
+}
+
+// reviewed returns the request closed with the status by the user.
+func reviewed(user string, request models.ChangeRequest, status, comment string) models.ChangeRequest {
+	now := time.Now().UTC()
+	request.Status = status
+	request.ReviewedBy = user
+	request.ReviewedAt = &now
+	request.ReviewComment = comment
+	return request
+}
+
+// apply makes an approved change on behalf of the user who proposed it.
+func (s *ChangeRequestService) apply(user string, proposal models.ChangeProposal) error {
+	switch proposal.Kind {
+	case models.ChangeKindConfig:
+		switch proposal.Operation {
+		case models.ChangeDelete:
+			return s.Configs.DeleteConfig(proposal.TargetID)
+		case models.ChangeArchive:
+			return s.Configs.ArchiveConfig(user, proposal.TargetID)
+		}
+	case models.ChangeKindSpecific:
+		switch proposal.Operation {
+		case models.ChangeCreate:
+			return s.Specifics.AddSpecificConfig(*proposal.SpecificConfig)
+		case models.ChangeUpdate:
+			return s.Specifics.UpdateSpecificConfig(proposal.TargetID, *proposal.SpecificConfig)
+		case models.ChangeDelete:
+			return s.Specifics.DeleteSpecificConfig(proposal.TargetID)
+		}
+	case models.ChangeKindExperiment:
+		switch proposal.Operation {
+		case models.ChangeCreate:
+			return s.Experiments.AddExperiment(*proposal.Experiment)
+		case models.ChangeUpdate:
+			return s.Experiments.UpdateExperiment(proposal.TargetID, *proposal.Experiment)
+		case models.ChangeDelete:
+			return s.Experiments.DeleteExperiment(proposal.TargetID)
+		}
+	case models.ChangeKindRollout:
+		_, err := s.Configs.SetRollout(user, proposal.TargetID, *proposal.Rollout)
+		return err
+	case models.ChangeKindImport:
+		_, err := s.Archives.Import(user, *proposal.Import, false)
+		return err
+	case models.ChangeKindPromotion:
+		_, err := s.Environments.Promote(user, *proposal.Promotion)
+		return err
+	}
+	return fmt.Errorf("unsupported change %s %s", proposal.Operation, proposal.Kind)
+}
+
+// GetChangeRequests lists requests with the given status, or all of them when
+// status is empty, oldest first.
+func (s *ChangeRequestService) GetChangeRequests(status string) []models.ChangeRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	requests := []models.ChangeRequest{}
+	for _, request := range s.requests {
+		if status == "" || request.Status == status {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ProposedAt.Before(requests[j].ProposedAt) })
+	return requests
+}
+
+func (s *ChangeRequestService) GetChangeRequestByID(id string) (models.ChangeRequest, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, exists := s.requests[id]
+	if !exists {
+		return models.ChangeRequest{}, errors.New("change request not found")
+	}
+	return request, nil
+}
+
+func (s *ChangeRequestService) save(request models.ChangeRequest) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(request); err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	encoder.Close()
+
+	filePath := filepath.Join(s.yamlDir, request.ID+".yaml")
+	if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func newChangeRequestID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate change request ID: %w", err)
+	}
+	return "cr-" + hex.EncodeToString(b), nil
+}
+
+func describeChange(proposal models.ChangeProposal) string {
+	return proposal.Operation + " " + proposal.Kind + " " + proposal.TargetID
+}
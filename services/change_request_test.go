@@ -0,0 +1,107 @@
+package services
+
+import (
+	"os"
+	"ssd-assignment-api/models"
+	"strings"
+	"testing"
+)
+
+func TestChangeRequestNeedsSecondApprover(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"/configs", "/specific_configs"} {
+		if err := os.Mkdir(dir+sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	audit, err := NewAuditLog(dir + "/audit.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := NewConfigService(dir+"/configs", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs.audit = audit
+	for _, id := range []string{"A", "B"} {
+		if err := configs.AddConfig(models.Config{ID: id, Actions: []models.Action{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	specifics, err := NewSpecificConfigService(dir+"/specific_configs", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := ChangeTargets{Configs: configs, Specifics: specifics}
+	changes, err := NewChangeRequestService(dir+"/change_requests", targets, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor := []string{RoleEditor}
+	publisher := []string{RoleEditor, RolePublisher}
+
+	percent := 10
+	proposal := models.ChangeProposal{Kind: models.ChangeKindRollout, Operation: models.ChangeApply, TargetID: "A", Rollout: &percent}
+	if _, err := changes.Propose("alice", editor, proposal); err == nil {
+		t.Error("an editor proposed a rollout")
+	}
+	rollout, err := changes.Propose("alice", publisher, proposal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, _ := configs.GetConfigByID("A"); config.Rollout != nil {
+		t.Fatal("proposing a rollout changed the config")
+	}
+
+	// Configs are created and updated through drafts only
+	if _, err := changes.Propose("alice", publisher, models.ChangeProposal{
+		Kind: models.ChangeKindConfig, Operation: models.ChangeUpdate, TargetID: "A",
+	}); err == nil {
+		t.Error("a config update was proposed")
+	}
+	if _, err := changes.Propose("alice", editor, models.ChangeProposal{
+		Kind: models.ChangeKindSpecific, Operation: models.ChangeCreate, SpecificConfig: &models.SpecificConfig{ID: "../s", DataSource: models.DataSource{
+			Pages: map[string]models.Mapping{"home": {IDs: []string{"A"}}},
+		}},
+	}); err == nil || !strings.Contains(err.Error(), "file name") {
+		t.Error("a specific config outside the directory was proposed")
+	}
+
+	// Nobody holds the approver role while ROLE_APPROVERS is unset
+	t.Setenv("ROLE_APPROVERS", "")
+	if _, err := changes.Approve("bob", rollout.ID, "ok"); err == nil {
+		t.Error("approved without the approver role")
+	}
+
+	t.Setenv("ROLE_APPROVERS", "alice,bob")
+	if _, err := changes.Approve("alice", rollout.ID, "ok"); err == nil {
+		t.Error("the proposer approved their own change")
+	}
+	if _, err := changes.Approve("bob", rollout.ID, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if config, _ := configs.GetConfigByID("A"); config.Rollout == nil || *config.Rollout != percent {
+		t.Errorf("rollout is %v after approval, want %d", config.Rollout, percent)
+	}
+
+	// A change that fails to apply stays pending, on disk too
+	remove, err := changes.Propose("alice", editor, models.ChangeProposal{Kind: models.ChangeKindConfig, Operation: models.ChangeDelete, TargetID: "B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := configs.DeleteConfig("B"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := changes.Approve("bob", remove.ID, "ok"); err == nil {
+		t.Fatal("a change that can not be applied was approved")
+	}
+	reloaded, err := NewChangeRequestService(dir+"/change_requests", targets, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, service := range []*ChangeRequestService{changes, reloaded} {
+		if request, _ := service.GetChangeRequestByID(remove.ID); request.Status != models.ChangePending {
+			t.Errorf("the failed change is %s, want pending", request.Status)
+		}
+	}
+}
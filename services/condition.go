@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+)
+
+// compiledCondition is a rule condition with its patterns compiled.
+type compiledCondition struct {
+	all   []*compiledCondition
+	any   []*compiledCondition
+	not   *compiledCondition
+	host  *pattern
+	url   *pattern
+	page  *pattern
+	query []queryMatcher
+
+	device    string
+	language  string
+	userAgent *pattern
+	headers   map[string]string
+	cookies   map[string]string
+}
+
+// compiledRule is a rule whose condition is evaluated per request. The
+// embedded mapping carries its IDs, weight and priority so matched rules score
+// exactly like datasource mappings.
+type compiledRule struct {
+	compiledMapping
+	condition *compiledCondition
+}
+
+func (pc patternCompiler) rules(rules []models.Rule, weight int) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		condition, err := pc.condition(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		name := rule.Name
+		if name == "" {
+			name = condition.String()
+		}
+
+		w, err := mappingWindow(rule.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		compiled = append(compiled, compiledRule{
+			compiledMapping: compiledMapping{
+				pattern:  &pattern{raw: name, kind: patternExact},
+				ids:      rule.IDs,
+				weight:   weight,
+				priority: rule.Priority,
+				stop:     rule.Stop,
+				window:   w,
+			},
+			condition: condition,
+		})
+	}
+	return compiled, nil
+}
+
+func (pc patternCompiler) condition(condition models.Condition) (*compiledCondition, error) {
+	query, err := compileQueryMatchers(condition.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &compiledCondition{
+		query:    query,
+		device:   strings.ToLower(condition.Device),
+		language: strings.ToLower(condition.Language),
+		headers:  condition.Headers,
+		cookies:  condition.Cookies,
+	}
+	empty := len(condition.Query) == 0 && compiled.device == "" && compiled.language == "" &&
+		len(condition.Headers) == 0 && len(condition.Cookies) == 0
+
+	if compiled.device != "" && !isDeviceClass(compiled.device) {
+		return nil, fmt.Errorf("unknown device class '%s'", condition.Device)
+	}
+
+	for _, child := range condition.All {
+		c, err := pc.condition(child)
+		if err != nil {
+			return nil, err
+		}
+		compiled.all = append(compiled.all, c)
+		empty = false
+	}
+	for _, child := range condition.Any {
+		c, err := pc.condition(child)
+		if err != nil {
+			return nil, err
+		}
+		compiled.any = append(compiled.any, c)
+		empty = false
+	}
+	if condition.Not != nil {
+		c, err := pc.condition(*condition.Not)
+		if err != nil {
+			return nil, err
+		}
+		compiled.not = c
+		empty = false
+	}
+
+	if condition.Host != "" {
+		if compiled.host, err = pc.host(condition.Host); err != nil {
+			return nil, err
+		}
+		empty = false
+	}
+	if condition.URL != "" {
+		if compiled.url, err = pc.url(condition.URL); err != nil {
+			return nil, err
+		}
+		empty = false
+	}
+	if condition.Page != "" {
+		if compiled.page, err = compilePagePattern(condition.Page); err != nil {
+			return nil, err
+		}
+		empty = false
+	}
+	if condition.UserAgent != "" {
+		if compiled.userAgent, err = compileUserAgentPattern(condition.UserAgent); err != nil {
+			return nil, err
+		}
+		empty = false
+	}
+
+	if empty {
+		return nil, errors.New("condition is empty")
+	}
+	return compiled, nil
+}
+
+// matches evaluates the condition against a request. A host, url or page
+// condition never matches when the request does not carry that value.
+func (c *compiledCondition) matches(req MatchRequest) bool {
+	if c.host != nil && !matchValue(c.host, req.Host) {
+		return false
+	}
+	if c.url != nil && !matchValue(c.url, req.URL) {
+		return false
+	}
+	if c.page != nil && !matchValue(c.page, req.Page) {
+		return false
+	}
+	if _, ok := matchQuery(c.query, req.Query); !ok {
+		return false
+	}
+	if c.device != "" && c.device != req.Device {
+		return false
+	}
+	if c.language != "" && !acceptsLanguage(req.Languages, c.language) {
+		return false
+	}
+	if c.userAgent != nil && !matchValue(c.userAgent, req.UserAgent) {
+		return false
+	}
+	for name, want := range c.headers {
+		values := req.Headers.Values(name)
+		if len(values) == 0 || (want != "" && !containsString(values, want)) {
+			return false
+		}
+	}
+	for name, want := range c.cookies {
+		value, present := req.Cookies[name]
+		if !present || (want != "" && value != want) {
+			return false
+		}
+	}
+
+	for _, child := range c.all {
+		if !child.matches(req) {
+			return false
+		}
+	}
+	if len(c.any) > 0 {
+		matched := false
+		for _, child := range c.any {
+			if child.matches(req) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if c.not != nil && c.not.matches(req) {
+		return false
+	}
+
+	return true
+}
+
+// String renders the condition for explanations, e.g.
+// "all(host=example.com, page=cart)".
+func (c *compiledCondition) String() string {
+	var parts []string
+	if c.host != nil {
+		parts = append(parts, "host="+c.host.raw)
+	}
+	if c.url != nil {
+		parts = append(parts, "url="+c.url.raw)
+	}
+	if c.page != nil {
+		parts = append(parts, "page="+c.page.raw)
+	}
+
+	parts = append(parts, queryString(c.query)...)
+	if c.device != "" {
+		parts = append(parts, "device="+c.device)
+	}
+	if c.language != "" {
+		parts = append(parts, "language="+c.language)
+	}
+	if c.userAgent != nil {
+		parts = append(parts, "userAgent="+c.userAgent.raw)
+	}
+	parts = appendNamed(parts, "header", c.headers)
+	parts = appendNamed(parts, "cookie", c.cookies)
+
+	if len(c.all) > 0 {
+		parts = append(parts, "all("+joinConditions(c.all)+")")
+	}
+	if len(c.any) > 0 {
+		parts = append(parts, "any("+joinConditions(c.any)+")")
+	}
+	if c.not != nil {
+		parts = append(parts, "not("+c.not.String()+")")
+	}
+
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "all(" + strings.Join(parts, ", ") + ")"
+}
+
+// appendNamed renders name/value conditions in name order.
+func appendNamed(parts []string, kind string, values map[string]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, kind+"."+name+"="+values[name])
+	}
+	return parts
+}
+
+func isDeviceClass(device string) bool {
+	switch device {
+	case DeviceMobile, DeviceTablet, DeviceDesktop, DeviceTV, DeviceBot, DeviceUnknown:
+		return true
+	}
+	return false
+}
+
+func joinConditions(conditions []*compiledCondition) string {
+	parts := make([]string, len(conditions))
+	for i, condition := range conditions {
+		parts[i] = condition.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func matchValue(p *pattern, value string) bool {
+	if value == "" {
+		return false
+	}
+	ok, _ := p.match(value)
+	return ok
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
@@ -3,26 +3,47 @@ package services
 import (
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"ssd-assignment-api/models"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 type ConfigService struct {
-	configs map[string]models.Config
-	mutex   sync.Mutex
-	yamlDir string // Only the YAML directory will be stored
+	configs  map[string]models.Config
+	windows  map[string]window // parsed schedules of configs that have one
+	drafts   map[string]models.ConfigDraft
+	archived map[string]models.Config
+	audit    *AuditLog
+	load     loadRecorder
+	sources  sourceIndex // the file and document every config was loaded from
+	labels   labelIndex
+	serving  atomic.Pointer[servingState]
+	mutex    sync.Mutex
+	yamlDir  string // Only the YAML directory will be stored
 }
 
-// NewConfigService now only accepts the YAML directory
-func NewConfigService(yamlDir string) (*ConfigService, error) {
+// servingState is what Allow decides on. It is replaced as a whole whenever a
+// schedule or rollout changes, so lookups read it without taking the mutex.
+type servingState struct {
+	windows  map[string]window
+	rollouts map[string]int
+}
+
+// NewConfigService loads the YAML directory; in lenient mode files that fail
+// to load are skipped and listed in the load report.
+func NewConfigService(yamlDir string, opts LoadOptions) (*ConfigService, error) {
 	service := &ConfigService{
-		configs: make(map[string]models.Config),
-		yamlDir: yamlDir,
+		configs:  make(map[string]models.Config),
+		windows:  make(map[string]window),
+		labels:   make(labelIndex),
+		drafts:   make(map[string]models.ConfigDraft),
+		archived: make(map[string]models.Config),
+		load:     newLoadRecorder(opts),
+		sources:  make(sourceIndex),
+		yamlDir:  yamlDir,
 	}
 
 	if err := service.loadConfigsFromYAML(); err != nil {
@@ -32,51 +53,94 @@ func NewConfigService(yamlDir string) (*ConfigService, error) {
 	return service, nil
 }
 
-// GetAllConfigs retrieves all configurations
+// GetAllConfigs retrieves all configurations ordered by ID
 func (s *ConfigService) GetAllConfigs() ([]models.Config, error) {
+	return s.FindConfigs(nil), nil
+}
+
+// FindConfigs returns the configurations whose labels match selector,
+// ordered by ID.
+func (s *ConfigService) FindConfigs(selector LabelSelector) []models.Config {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	var configList []models.Config
-	for _, config := range s.configs {
-		configList = append(configList, config)
+	configList := []models.Config{}
+	for _, id := range s.labels.candidates(selector, sortedKeys(s.configs)) {
+		if config := s.configs[id]; selector.Matches(config.Labels) {
+			configList = append(configList, config)
+		}
 	}
+	return configList
+}
+
+// putConfig puts a config in memory and in the label index. Callers must
+// hold the mutex.
+func (s *ConfigService) putConfig(id string, config models.Config) {
+	s.labels.remove(id, s.configs[id].Labels)
+	s.configs[id] = config
+	s.labels.add(id, config.Labels)
+}
 
-	return configList, nil
+// dropConfig removes a config from memory and the label index. Callers must
+// hold the mutex.
+func (s *ConfigService) dropConfig(id string) {
+	s.labels.remove(id, s.configs[id].Labels)
+	delete(s.configs, id)
+	delete(s.windows, id)
 }
 func (s *ConfigService) loadConfigsFromYAML() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// Read all files in the YAML directory
-	files, err := os.ReadDir(s.yamlDir)
-	if err != nil {
-		return fmt.Errorf("YAML directory could not be read: %w", err)
-	}
-
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
-			continue // Process only .yaml files
-		}
-
-		// Create the file path
-		filePath := filepath.Join(s.yamlDir, file.Name())
-		yamlData, err := os.ReadFile(filePath)
+	// Every document of the .yaml, .yml and .json files is a config
+	err := s.load.loadDocuments("config", s.yamlDir, func(src source, doc *yaml.Node, single bool) error {
+		config, w, err := decodeConfig(doc)
 		if err != nil {
-			return fmt.Errorf("%s file could not be read: %w", filePath, err)
+			return err
 		}
-
-		// Unmarshal YAML data to Config struct
-		var config models.Config
-		if err := yaml.Unmarshal(yamlData, &config); err != nil {
-			return fmt.Errorf("%s file could not be parsed: %w", filePath, err)
+		if err := s.load.checkID("config", src, single, &config.ID); err != nil {
+			return err
 		}
 
 		// Add Config to memory
-		s.configs[config.ID] = config
+		src.resource = isResource(doc)
+		s.putConfig(config.ID, config)
+		s.storeWindow(config.ID, w)
+		s.sources[config.ID] = src
+		return nil
+	})
+	s.publishServing()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return s.loadWorkflowFromYAML()
+}
+
+// decodeConfig decodes a config document in either form and parses its
+// schedule.
+func decodeConfig(doc *yaml.Node) (models.Config, *window, error) {
+	config, err := decodeConfigDocument(doc)
+	if err != nil {
+		return models.Config{}, nil, fmt.Errorf("document could not be parsed: %w", err)
+	}
+
+	w, err := scheduleWindow(config)
+	if err != nil {
+		return models.Config{}, nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if err := validateRollout(config.Rollout); err != nil {
+		return models.Config{}, nil, err
+	}
+	return config, w, nil
+}
+
+// LoadReport returns the outcome of loading the YAML directory at startup.
+func (s *ConfigService) LoadReport() models.LoadReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.load.report()
 }
 
 // GetConfigByID retrieves a configuration by its ID
@@ -100,14 +164,13 @@ func (s *ConfigService) AddConfig(config models.Config) error {
 	if _, exists := s.configs[config.ID]; exists {
 		return fmt.Errorf("ID '%s' already exists", config.ID)
 	}
+	if err := checkFileName(config.ID); err != nil {
+		return err
+	}
 
-	// Create YAML file
-	if err := s.createYAMLFile(config); err != nil {
+	if err := s.storeConfig(config.ID, config); err != nil {
 		return fmt.Errorf("YAML file could not be created: %w", err)
 	}
-
-	// Add to memory
-	s.configs[config.ID] = config
 	return nil
 }
 
@@ -121,13 +184,33 @@ func (s *ConfigService) UpdateConfig(id string, config models.Config) error {
 		return errors.New("configuration not found")
 	}
 
-	// Update YAML file
-	if err := s.updateYAMLFile(config); err != nil {
+	if err := s.storeConfig(id, config); err != nil {
 		return fmt.Errorf("YAML could not be updated: %w", err)
 	}
+	return nil
+}
 
-	// Update memory
-	s.configs[id] = config
+// storeConfig validates a config, writes its live YAML file and puts it in
+// memory under id, updating its timestamps. Callers must hold the mutex.
+func (s *ConfigService) storeConfig(id string, config models.Config) error {
+	previous, exists := s.configs[id]
+	touch(&config.ResourceMeta, previous.ResourceMeta, exists)
+
+	w, err := scheduleWindow(config)
+	if err != nil {
+		return err
+	}
+	if err := validateRollout(config.Rollout); err != nil {
+		return err
+	}
+
+	if err := s.saveYAMLFile(config); err != nil {
+		return err
+	}
+
+	s.putConfig(id, config)
+	s.storeWindow(id, w)
+	s.publishServing()
 	return nil
 }
 
@@ -147,65 +230,184 @@ func (s *ConfigService) DeleteConfig(id string) error {
 	}
 
 	// Remove from memory
-	delete(s.configs, id)
+	s.dropConfig(id)
+	s.publishServing()
 	return nil
 }
 
-// createYAMLFile creates a YAML file for the new config based on the config's ID
-func (s *ConfigService) createYAMLFile(config models.Config) error {
-	filePath := fmt.Sprintf("%s/%s.yaml", s.yamlDir, config.ID)
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
+// scheduleWindow parses the schedule of a config; it returns nil when the
+// config is always active.
+func scheduleWindow(config models.Config) (*window, error) {
+	if config.Schedule.IsZero() {
+		return nil, nil
 	}
-	defer file.Close()
 
-	// Marshall config to YAML
-	yamlData, err := yaml.Marshal(&config)
+	w, err := parseSchedule(config.Schedule)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &w, nil
+}
 
-	// Write the YAML data to file
-	_, err = file.Write(yamlData)
-	if err != nil {
+// storeWindow records the schedule of a config. Callers must hold the mutex.
+func (s *ConfigService) storeWindow(id string, w *window) {
+	if w == nil {
+		delete(s.windows, id)
+		return
+	}
+	s.windows[id] = *w
+}
+
+// publishServing replaces the state Allow reads with the current schedules
+// and rollouts. Callers must hold the mutex.
+func (s *ConfigService) publishServing() {
+	state := &servingState{
+		windows:  make(map[string]window, len(s.windows)),
+		rollouts: make(map[string]int),
+	}
+	for id, w := range s.windows {
+		state.windows[id] = w
+	}
+	for id, config := range s.configs {
+		if config.Rollout != nil && *config.Rollout < 100 {
+			state.rollouts[id] = *config.Rollout
+		}
+	}
+	s.serving.Store(state)
+}
+
+// validateConfig checks the parts of a config that are parsed on load and
+// its labels. Actions are only checked by the linter, see validateActions.
+func validateConfig(config models.Config) error {
+	if _, err := scheduleWindow(config); err != nil {
 		return err
 	}
+	if err := validateRollout(config.Rollout); err != nil {
+		return err
+	}
+	if err := validateLabels(config.Labels); err != nil {
+		return err
+	}
+	return nil
+}
 
+func validateRollout(rollout *int) error {
+	if rollout != nil && (*rollout < 0 || *rollout > 100) {
+		return errors.New("rollout must be between 0 and 100")
+	}
 	return nil
 }
 
-// updateYAMLFile updates the YAML file corresponding to the config's ID
-func (s *ConfigService) updateYAMLFile(config models.Config) error {
-	filePath := fmt.Sprintf("%s/%s.yaml", s.yamlDir, config.ID)
-	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0666)
-	if err != nil {
-		return err
+// SetAuditLog sets the log rollout changes are recorded in.
+func (s *ConfigService) SetAuditLog(audit *AuditLog) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.audit = audit
+}
+
+// SetRollout changes the rollout percentage of a config and records the
+// change in the audit log.
+func (s *ConfigService) SetRollout(user, id string, percent int) (models.Config, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	config, exists := s.configs[id]
+	if !exists {
+		return models.Config{}, errors.New("configuration not found")
+	}
+	if err := validateRollout(&percent); err != nil {
+		return models.Config{}, err
 	}
-	defer file.Close()
 
-	// Marshall config to YAML
-	yamlData, err := yaml.Marshal(&config)
-	if err != nil {
-		return err
+	previous := "100"
+	if config.Rollout != nil {
+		previous = fmt.Sprint(*config.Rollout)
+	}
+	config.Rollout = &percent
+	touch(&config.ResourceMeta, config.ResourceMeta, true)
+	if err := s.saveYAMLFile(config); err != nil {
+		return models.Config{}, fmt.Errorf("YAML could not be updated: %w", err)
+	}
+	s.putConfig(id, config)
+	s.publishServing()
+
+	if s.audit != nil {
+		detail := fmt.Sprintf("%s%% -> %d%%", previous, percent)
+		if err := s.audit.Record(user, "config.rollout", id, detail); err != nil {
+			return config, fmt.Errorf("rollout changed but could not be audited: %w", err)
+		}
+	}
+	return config, nil
+}
+
+// Allow implements ConfigFilter: configs are only served inside their
+// schedule and to the visitors inside their rollout percentage. References
+// to unknown configs are allowed here and reported by the resolver instead.
+// Allow reads the published serving state and never waits on writers.
+func (s *ConfigService) Allow(ref string, req MatchRequest) (bool, string) {
+	state := s.serving.Load()
+
+	id := ConfigIDFromRef(ref)
+	if w, scheduled := state.windows[id]; scheduled && !w.active(req.At) {
+		return false, "config is outside its schedule"
+	}
+
+	rollout, partial := state.rollouts[id]
+	if !partial {
+		return true, ""
+	}
+	if req.VisitorID == "" {
+		return false, "config is rolled out partially and there is no visitor ID"
+	}
+	if visitorBucket("rollout:"+id, req.VisitorID, 100) >= rollout {
+		return false, fmt.Sprintf("visitor is outside the %d%% rollout", rollout)
 	}
+	return true, ""
+}
+
+func (s *ConfigService) scheduleTransitions(now time.Time) []models.ScheduleTransition {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var transitions []models.ScheduleTransition
+	for _, id := range sortedKeys(s.windows) {
+		transitions = s.windows[id].transitions(now, models.ScheduleTransition{Kind: "config", ConfigID: id}, transitions)
+	}
+	return transitions
+}
 
-	// Write the YAML data to file
-	_, err = file.Write(yamlData)
+// saveYAMLFile writes a config back to the document it was loaded from, in
+// the form it was loaded in, or to <id>.yaml when it is new. An existing
+// document is updated in place, keeping its comments and formatting. Only
+// resources carry the timestamps.
+func (s *ConfigService) saveYAMLFile(config models.Config) error {
+	src, err := s.sources.get(s.yamlDir, config.ID)
 	if err != nil {
 		return err
 	}
-
+	flat := config.WithoutTimestamps()
+	var value interface{} = &flat
+	if src.resource {
+		value = models.NewConfigResource(config)
+	}
+	if err := writeDocument(src, value); err != nil {
+		return err
+	}
+	s.sources[config.ID] = src
 	return nil
 }
 
-// deleteYAMLFile deletes the YAML file corresponding to the config's ID
+// deleteYAMLFile removes the document of a config, and its file when no
+// other document is left in it.
 func (s *ConfigService) deleteYAMLFile(id string) error {
-	filePath := fmt.Sprintf("%s/%s.yaml", s.yamlDir, id)
-	err := os.Remove(filePath)
+	src, err := s.sources.get(s.yamlDir, id)
 	if err != nil {
 		return err
 	}
-
+	if _, err := removeDocument(src); err != nil {
+		return err
+	}
+	s.sources.remove(id)
 	return nil
 }
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"ssd-assignment-api/models"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Drafts and archived configurations live in subdirectories of the YAML
+// directory, which the live loader skips.
+const (
+	draftDir   = "drafts"
+	archiveDir = "archived"
+)
+
+// loadWorkflowFromYAML reads the drafts and archived configurations. Callers
+// must hold the mutex.
+func (s *ConfigService) loadWorkflowFromYAML() error {
+	drafts, err := readYAMLDir[models.ConfigDraft](filepath.Join(s.yamlDir, draftDir), &s.load, "draft")
+	if err != nil {
+		return err
+	}
+	for _, draft := range drafts {
+		s.drafts[draft.Config.ID] = draft
+	}
+
+	archived, err := readYAMLDir[models.Config](filepath.Join(s.yamlDir, archiveDir), &s.load, "archived")
+	if err != nil {
+		return err
+	}
+	for _, config := range archived {
+		s.archived[config.ID] = config
+	}
+	return nil
+}
+
+// SaveDraft stores a work-in-progress revision without touching the live
+// configuration. Editing a draft that is in review sends it back to draft.
+func (s *ConfigService) SaveDraft(user string, config models.Config) (models.ConfigDraft, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if config.ID == "" {
+		return models.ConfigDraft{}, errors.New("configuration ID is required")
+	}
+	if err := checkFileName(config.ID); err != nil {
+		return models.ConfigDraft{}, err
+	}
+	if err := validateConfig(config); err != nil {
+		return models.ConfigDraft{}, err
+	}
+
+	draft := models.ConfigDraft{
+		Config:    config,
+		State:     models.StateDraft,
+		UpdatedBy: user,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := s.writeDraft(draft); err != nil {
+		return models.ConfigDraft{}, err
+	}
+	s.drafts[config.ID] = draft
+	return draft, nil
+}
+
+// GetDraft returns the draft of a configuration.
+func (s *ConfigService) GetDraft(id string) (models.ConfigDraft, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	draft, exists := s.drafts[id]
+	if !exists {
+		return models.ConfigDraft{}, errors.New("draft not found")
+	}
+	return draft, nil
+}
+
+// GetAllDrafts returns every draft ordered by ID.
+func (s *ConfigService) GetAllDrafts() []models.ConfigDraft {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	drafts := make([]models.ConfigDraft, 0, len(s.drafts))
+	for _, draft := range s.drafts {
+		drafts = append(drafts, draft)
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].Config.ID < drafts[j].Config.ID })
+	return drafts
+}
+
+// DiscardDraft deletes the draft of a configuration.
+func (s *ConfigService) DiscardDraft(user, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.drafts[id]; !exists {
+		return errors.New("draft not found")
+	}
+	if err := removeWorkflowFile(s.draftPath(id)); err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+	delete(s.drafts, id)
+	return s.record(user, "config.discard", id, "")
+}
+
+// SubmitDraft moves a draft into review.
+func (s *ConfigService) SubmitDraft(user, id string) (models.ConfigDraft, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	draft, exists := s.drafts[id]
+	if !exists {
+		return models.ConfigDraft{}, errors.New("draft not found")
+	}
+	if draft.State != models.StateDraft {
+		return models.ConfigDraft{}, fmt.Errorf("only drafts can be submitted, the config is %s", draft.State)
+	}
+
+	draft.State = models.StateInReview
+	draft.SubmittedBy = user
+	if err := s.writeDraft(draft); err != nil {
+		return models.ConfigDraft{}, err
+	}
+	s.drafts[id] = draft
+	return draft, s.record(user, "config.submit", id, "")
+}
+
+// PublishDraft makes a reviewed draft the live configuration. Publishing is
+// the review of the draft, so the user must be an approver who neither
+// edited the draft last nor submitted it.
+func (s *ConfigService) PublishDraft(user, id string) (models.Config, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	draft, exists := s.drafts[id]
+	if !exists {
+		return models.Config{}, errors.New("draft not found")
+	}
+	if draft.State != models.StateInReview {
+		return models.Config{}, errors.New("only drafts in review can be published")
+	}
+	if !HasRole(RolesFor(user), RoleApprover) {
+		return models.Config{}, errors.New("only approvers can publish drafts")
+	}
+	if draft.UpdatedBy == user || draft.SubmittedBy == user {
+		return models.Config{}, errors.New("a draft can not be published by the user who edited or submitted it")
+	}
+
+	if err := s.storeConfig(id, draft.Config); err != nil {
+		return models.Config{}, fmt.Errorf("config could not be published: %w", err)
+	}
+	if err := removeWorkflowFile(s.draftPath(id)); err != nil {
+		return models.Config{}, fmt.Errorf("config published but its draft could not be removed: %w", err)
+	}
+	delete(s.drafts, id)
+
+	if _, archived := s.archived[id]; archived {
+		removeWorkflowFile(s.archivePath(id))
+		delete(s.archived, id)
+	}
+	return draft.Config, s.record(user, "config.publish", id, "")
+}
+
+// ArchiveConfig stops serving a published configuration but keeps it, so it
+// can be edited and published again later.
+func (s *ConfigService) ArchiveConfig(user, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	config, exists := s.configs[id]
+	if !exists {
+		return errors.New("configuration not found")
+	}
+
+	// The document is moved so its comments and formatting are kept
+	src, err := s.sources.get(s.yamlDir, id)
+	if err != nil {
+		return err
+	}
+	path, err := s.archivePath(id)
+	if err != nil {
+		return fmt.Errorf("config could not be archived: %w", err)
+	}
+	if err := moveDocument(src, path); err != nil {
+		return fmt.Errorf("config could not be archived: %w", err)
+	}
+	s.sources.remove(id)
+	s.dropConfig(id)
+	s.publishServing()
+	s.archived[id] = config
+	return s.record(user, "config.archive", id, "")
+}
+
+// ConfigStatus reports the workflow state of a configuration.
+func (s *ConfigService) ConfigStatus(id string) (models.ConfigStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, published := s.configs[id]
+	draft, hasDraft := s.drafts[id]
+	_, archived := s.archived[id]
+
+	status := models.ConfigStatus{ID: id, Published: published, HasDraft: hasDraft}
+	switch {
+	case hasDraft:
+		status.State = draft.State
+	case published:
+		status.State = models.StatePublished
+	case archived:
+		status.State = models.StateArchived
+	default:
+		return status, errors.New("configuration not found")
+	}
+	return status, nil
+}
+
+// DiffDraft compares the draft of a configuration with its published
+// revision. A missing side diffs as an empty document.
+func (s *ConfigService) DiffDraft(id string) (models.ConfigDiff, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	diff := models.ConfigDiff{ID: id}
+	var before, after []byte
+	// The timestamps of the published revision are not part of the change
+	if config, exists := s.configs[id]; exists {
+		diff.Published = &config
+		content := config.WithoutTimestamps()
+		before, _ = yaml.Marshal(&content)
+	}
+	if draft, exists := s.drafts[id]; exists {
+		diff.Draft = &draft.Config
+		content := draft.Config.WithoutTimestamps()
+		after, _ = yaml.Marshal(&content)
+	}
+	if diff.Published == nil && diff.Draft == nil {
+		return diff, errors.New("configuration not found")
+	}
+
+	diff.Lines, diff.Changed = diffLines(string(before), string(after))
+	return diff, nil
+}
+
+func (s *ConfigService) record(user, action, target, detail string) error {
+	if s.audit == nil {
+		return nil
+	}
+	return s.audit.Record(user, action, target, detail)
+}
+
+func (s *ConfigService) draftPath(id string) (string, error) {
+	return workflowPath(filepath.Join(s.yamlDir, draftDir), id)
+}
+
+func (s *ConfigService) archivePath(id string) (string, error) {
+	return workflowPath(filepath.Join(s.yamlDir, archiveDir), id)
+}
+
+// workflowPath returns the file of a draft or archived config: an existing
+// <id>.yaml, <id>.yml or <id>.json, or <id>.yaml for a new one. IDs that
+// would leave dir are rejected.
+func workflowPath(dir, id string) (string, error) {
+	if err := checkFileName(id); err != nil {
+		return "", err
+	}
+	for _, ext := range documentExtensions {
+		path := filepath.Join(dir, id+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return filepath.Join(dir, id+".yaml"), nil
+}
+
+// removeWorkflowFile deletes the draft or archived file at the path.
+func removeWorkflowFile(path string, err error) error {
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *ConfigService) writeDraft(draft models.ConfigDraft) error {
+	path, err := s.draftPath(draft.Config.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeYAMLFile(path, draft); err != nil {
+		return fmt.Errorf("draft could not be saved: %w", err)
+	}
+	return nil
+}
+
+// writeYAMLFile writes value as the only document of a file, as JSON when
+// the file has the .json extension.
+func writeYAMLFile(path string, value interface{}) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return writeDocuments(path, []*yamlv3.Node{&doc})
+}
+
+// readYAMLDir parses every .yaml, .yml and .json file of a directory; a
+// missing directory holds nothing. Files that fail to load are passed to the
+// recorder as kind.
+func readYAMLDir[T any](dir string, load *loadRecorder, kind string) ([]T, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s could not be read: %w", dir, err)
+	}
+
+	var values []T
+	for _, file := range files {
+		if file.IsDir() || !isDocumentFile(file.Name()) {
+			continue
+		}
+
+		filePath := filepath.Join(dir, file.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			if err := load.fail(kind, filePath, fmt.Errorf("file could not be read: %w", err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var value T
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			if err := load.fail(kind, filePath, fmt.Errorf("file could not be parsed: %w", err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		values = append(values, value)
+		load.loaded++
+	}
+	return values, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import "strings"
+
+// diffLines returns a line diff of a and b based on their longest common
+// subsequence. Every line is prefixed with "-" (only in a), "+" (only in b)
+// or " " (in both).
+func diffLines(a, b string) ([]string, bool) {
+	x, y := splitDiffLines(a), splitDiffLines(b)
+
+	// lcs[i][j] is the length of the common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(x)+len(y))
+	changed := false
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, " "+x[i])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+x[i])
+			i++
+			changed = true
+		default:
+			lines = append(lines, "+"+y[j])
+			j++
+			changed = true
+		}
+	}
+	return lines, changed
+}
+
+func splitDiffLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"ssd-assignment-api/models"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// documentExtensions are the file types the loaders read. JSON files hold a
+// single document with the same keys as the YAML form.
+var documentExtensions = []string{".yaml", ".yml", ".json"}
+
+// isDocumentFile reports whether a file name has one of the extensions the
+// loaders read.
+func isDocumentFile(name string) bool {
+	return containsString(documentExtensions, filepath.Ext(name))
+}
+
+// isFileName reports whether an ID can name a file of its own: a plain base
+// name that does not start with a dot.
+func isFileName(id string) bool {
+	return id != "" && id == filepath.Base(id) && !strings.HasPrefix(id, ".")
+}
+
+// checkFileName returns an error for IDs isFileName rejects.
+func checkFileName(id string) error {
+	if !isFileName(id) {
+		return fmt.Errorf("id '%s' can not be used as a file name", id)
+	}
+	return nil
+}
+
+// source is where a loaded config lives: its file, the index of its
+// document within that file and whether the document is in the resource form.
+type source struct {
+	path     string
+	index    int
+	resource bool
+}
+
+func (s source) String() string {
+	if s.index == 0 {
+		return s.path
+	}
+	return fmt.Sprintf("%s (document %d)", s.path, s.index+1)
+}
+
+// readDocuments parses every document of a file. Empty documents, such as
+// the one after a trailing "---", are returned as nil so indexes stay
+// aligned with the file.
+func readDocuments(path string) ([]*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].ShortTag() == "!!null" {
+			docs = append(docs, nil)
+			continue
+		}
+		docs = append(docs, &doc)
+	}
+}
+
+// writeDocument stores value as the document of src. An existing document is
+// merged node by node so its comments and formatting are kept; the other
+// documents of the file are left as they are.
+func writeDocument(src source, value interface{}) error {
+	var fresh yaml.Node
+	if err := fresh.Encode(value); err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+
+	// A file that no longer parses may hold other documents, so it is left
+	// for someone to fix rather than overwritten
+	docs, err := readDocuments(src.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s could not be parsed and is not overwritten: %w", src.path, err)
+	}
+	for len(docs) <= src.index {
+		docs = append(docs, nil)
+	}
+
+	if current := docs[src.index]; current != nil {
+		current.Content[0] = mergeNodes(current.Content[0], &fresh)
+	} else {
+		docs[src.index] = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&fresh}}
+	}
+	return writeDocuments(src.path, docs)
+}
+
+// removeDocument deletes the document of src, and the file when nothing else
+// is left in it. It reports whether the file was removed.
+func removeDocument(src source) (bool, error) {
+	docs, err := readDocuments(src.path)
+	if err != nil {
+		return false, err
+	}
+
+	var rest []*yaml.Node
+	for i, doc := range docs {
+		if i != src.index {
+			rest = append(rest, doc)
+		}
+	}
+	empty := true
+	for _, doc := range rest {
+		if doc != nil {
+			empty = false
+		}
+	}
+	if empty {
+		return true, os.Remove(src.path)
+	}
+	return false, writeDocuments(src.path, rest)
+}
+
+// moveDocument writes the document of src to the YAML file dest, comments
+// included, and removes it from its file.
+func moveDocument(src source, dest string) error {
+	docs, err := readDocuments(src.path)
+	if err != nil {
+		return err
+	}
+	if src.index >= len(docs) || docs[src.index] == nil {
+		return fmt.Errorf("%s has no such document", src)
+	}
+
+	if err := writeDocuments(dest, []*yaml.Node{docs[src.index]}); err != nil {
+		return err
+	}
+	_, err = removeDocument(src)
+	return err
+}
+
+// writeDocuments encodes the documents of a file in the format of its
+// extension, creating its directory if needed. A YAML file without a final
+// newline is written without one.
+func writeDocuments(path string, docs []*yaml.Node) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var present []*yaml.Node
+	for _, doc := range docs {
+		if doc == nil {
+			doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!null"}}}
+		}
+		present = append(present, doc)
+	}
+
+	if filepath.Ext(path) == ".json" {
+		if len(present) != 1 {
+			return fmt.Errorf("%s: a JSON file holds a single document", path)
+		}
+		var buf bytes.Buffer
+		if err := writeNodeJSON(&buf, present[0].Content[0]); err != nil {
+			return err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+			return err
+		}
+		indented.WriteString("\n")
+		return os.WriteFile(path, indented.Bytes(), 0644)
+	}
+
+	finalNewline := true
+	if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+		finalNewline = bytes.HasSuffix(data, []byte("\n"))
+	}
+
+	// The emitter escapes runes outside the Basic Multilingual Plane, such as
+	// emoji, and gives up block style for them, so they are swapped for
+	// private use runes while encoding
+	root := &yaml.Node{Kind: yaml.SequenceNode, Content: present}
+	restore := maskWideRunes(root)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, doc := range present {
+		if err := encoder.Encode(doc); err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+	}
+	encoder.Close()
+
+	output := restore.Replace(buf.String())
+	if !finalNewline {
+		output = strings.TrimSuffix(output, "\n")
+	}
+	return os.WriteFile(path, []byte(output), 0644)
+}
+
+// writeNodeJSON writes a node as compact JSON, keeping the key order of
+// mappings.
+func writeNodeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return writeNodeJSON(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeNodeJSON(buf, node.Alias)
+
+	case yaml.MappingNode:
+		buf.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			buf.Write(key)
+			buf.WriteString(":")
+			if err := writeNodeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+
+	case yaml.SequenceNode:
+		buf.WriteString("[")
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeNodeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+
+	default:
+		switch node.ShortTag() {
+		case "!!null":
+			buf.WriteString("null")
+		case "!!bool":
+			value, err := strconv.ParseBool(node.Value)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(strconv.FormatBool(value))
+		case "!!int", "!!float":
+			var number interface{}
+			if err := node.Decode(&number); err != nil {
+				return err
+			}
+			data, err := json.Marshal(number)
+			if err != nil {
+				return err
+			}
+			buf.Write(data)
+		default:
+			data, _ := json.Marshal(node.Value)
+			buf.Write(data)
+		}
+	}
+	return nil
+}
+
+// sourceIndex maps IDs to the document they were loaded from.
+type sourceIndex map[string]source
+
+// get returns the source of id, or <dir>/<id>.yaml for a new config. New IDs
+// that would leave dir are rejected.
+func (s sourceIndex) get(dir, id string) (source, error) {
+	if src, ok := s[id]; ok {
+		return src, nil
+	}
+	if err := checkFileName(id); err != nil {
+		return source{}, err
+	}
+	return source{path: filepath.Join(dir, id+".yaml")}, nil
+}
+
+// remove forgets id and moves the documents after it in the same file up
+// by one, as removeDocument did in the file.
+func (s sourceIndex) remove(id string) {
+	src, ok := s[id]
+	if !ok {
+		return
+	}
+	delete(s, id)
+	for other, o := range s {
+		if o.path == src.path && o.index > src.index {
+			o.index--
+			s[other] = o
+		}
+	}
+}
+
+// documentStep is a validated document together with the revision it
+// replaces, so a batch of writes can be undone when a later one fails.
+type documentStep struct {
+	kind, id                   string
+	exists                     bool
+	config, previousConfig     models.Config
+	specific, previousSpecific models.SpecificConfig
+}
+
+func (step documentStep) apply(configs *ConfigService, specifics *SpecificConfigService) error {
+	switch {
+	case step.kind == models.ChangeKindConfig && step.exists:
+		return configs.UpdateConfig(step.id, step.config)
+	case step.kind == models.ChangeKindConfig:
+		return configs.AddConfig(step.config)
+	case step.exists:
+		return specifics.UpdateSpecificConfig(step.id, step.specific)
+	default:
+		return specifics.AddSpecificConfig(step.specific)
+	}
+}
+
+// rollback restores the revision the step replaced.
+func (step documentStep) rollback(configs *ConfigService, specifics *SpecificConfigService) error {
+	switch {
+	case step.kind == models.ChangeKindConfig && step.exists:
+		return configs.UpdateConfig(step.id, step.previousConfig)
+	case step.kind == models.ChangeKindConfig:
+		return configs.DeleteConfig(step.id)
+	case step.exists:
+		return specifics.UpdateSpecificConfig(step.id, step.previousSpecific)
+	default:
+		return specifics.DeleteSpecificConfig(step.id)
+	}
+}
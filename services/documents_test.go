@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"ssd-assignment-api/models"
+	"strings"
+	"testing"
+)
+
+func TestWriteDocumentKeepsUnparsableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "A.yaml")
+	broken := "id: A\nactions: []\n---\nid: B\nactions: [\n"
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeDocument(source{path: path}, models.Config{ID: "A", Actions: []models.Action{}}); err == nil {
+		t.Error("an unparsable file was written over")
+	}
+	if data, _ := os.ReadFile(path); string(data) != broken {
+		t.Errorf("file changed to %q", data)
+	}
+}
+
+func TestWorkflowReadsAllDocumentExtensions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"drafts/A.yml":    "config:\n  id: A\n  actions: []\nstate: draft\n",
+		"drafts/B.json":   `{"config": {"id": "B", "actions": []}, "state": "in_review"}`,
+		"archived/C.json": `{"id": "C", "actions": []}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configs, err := NewConfigService(dir, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs.GetAllDrafts()) != 2 {
+		t.Errorf("loaded %d drafts, want 2", len(configs.GetAllDrafts()))
+	}
+	if status, err := configs.ConfigStatus("C"); err != nil || status.State != "archived" {
+		t.Errorf("C is %+v (%v), want archived", status, err)
+	}
+
+	// A draft is saved back to the file it was loaded from, in its format
+	if _, err := configs.SaveDraft("alice", models.Config{ID: "B", Actions: []models.Action{}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "drafts/B.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var draft models.ConfigDraft
+	if err := json.Unmarshal(data, &draft); err != nil || draft.UpdatedBy != "alice" {
+		t.Errorf("B.json holds %s (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "drafts/B.yaml")); !os.IsNotExist(err) {
+		t.Error("the draft was saved to a second file")
+	}
+}
+
+func TestTimestampsOnlyInResourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	resource := "apiVersion: ssd/v1\nkind: Config\nmetadata:\n  id: R\nspec:\n  actions: []\n"
+	if err := os.WriteFile(filepath.Join(dir, "R.yaml"), []byte(resource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configs, err := NewConfigService(dir, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := configs.AddConfig(models.Config{ID: "A", Actions: []models.Action{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := configs.UpdateConfig("R", models.Config{ID: "R", Actions: []models.Action{}}); err != nil {
+		t.Fatal(err)
+	}
+	if config, _ := configs.GetConfigByID("A"); config.CreatedAt == nil || config.UpdatedAt == nil {
+		t.Error("the flat config has no timestamps in memory")
+	}
+
+	read := func(file string) string {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if flat := read("A.yaml"); strings.Contains(flat, "createdAt") || strings.Contains(flat, "updatedAt") {
+		t.Errorf("the flat file has timestamps:\n%s", flat)
+	}
+	// R was loaded without createdAt, so only its update time is known
+	if resource := read("R.yaml"); !strings.Contains(resource, "updatedAt") {
+		t.Errorf("the resource has no timestamps:\n%s", resource)
+	}
+}
+
+func TestIDsOutsideTheDirectoryAreRejected(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"configs", "specifics"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configs, err := NewConfigService(filepath.Join(dir, "configs"), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	specifics, err := NewSpecificConfigService(filepath.Join(dir, "specifics"), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"../evil", ".hidden"} {
+		config := models.Config{ID: id, Actions: []models.Action{}}
+		if _, err := configs.SaveDraft("alice", config); err == nil {
+			t.Errorf("draft %q was saved", id)
+		}
+		if err := configs.AddConfig(config); err == nil {
+			t.Errorf("config %q was added", id)
+		}
+		if err := specifics.AddSpecificConfig(models.SpecificConfig{ID: id}); err == nil {
+			t.Errorf("specific config %q was added", id)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "configs", "evil.yaml")); !os.IsNotExist(err) {
+		t.Error("a draft was written into the live directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.yaml")); !os.IsNotExist(err) {
+		t.Error("a file was written outside the directories")
+	}
+}
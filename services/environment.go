@@ -0,0 +1,387 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+	"sync"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvironments is the promotion chain used when ENVIRONMENTS is unset.
+var DefaultEnvironments = []string{"dev", "staging", "prod"}
+
+// Environment is one stage of the promotion chain with its own storage.
+type Environment struct {
+	Name      string
+	Configs   *ConfigService
+	Specifics *SpecificConfigService
+}
+
+// EnvironmentService holds every environment in promotion order. The last
+// one is production and keeps the top-level config_files and
+// specific_configs directories; the others live in environments/<name>/.
+// Only the served environment is kept in memory for good; the others may be
+// written by the servers that serve them and are read again from disk
+// before promoting or comparing.
+type EnvironmentService struct {
+	environments []*Environment
+	served       string
+	opts         LoadOptions
+	audit        *AuditLog
+	mutex        sync.Mutex
+}
+
+// NewEnvironmentService loads every environment with the given options.
+func NewEnvironmentService(names []string, served string, opts LoadOptions, audit *AuditLog) (*EnvironmentService, error) {
+	if len(names) == 0 {
+		names = DefaultEnvironments
+	}
+	if served == "" {
+		served = names[len(names)-1]
+	}
+
+	service := &EnvironmentService{served: served, opts: opts, audit: audit}
+	for i, name := range names {
+		env := &Environment{Name: name}
+		if err := service.load(env, i == len(names)-1); err != nil {
+			return nil, err
+		}
+		service.environments = append(service.environments, env)
+	}
+
+	if _, err := service.Get(served); err != nil {
+		return nil, fmt.Errorf("served environment: %w", err)
+	}
+	return service, nil
+}
+
+// load reads the storage of an environment. Only production must exist; the
+// directories of the other environments are created when first written.
+func (s *EnvironmentService) load(env *Environment, production bool) error {
+	opts := s.opts
+	opts.AllowMissing = !production
+	configDir, specificDir := EnvironmentDirs(env.Name, production)
+
+	configs, err := NewConfigService(configDir, opts)
+	if err != nil {
+		return fmt.Errorf("environment %s: %w", env.Name, err)
+	}
+	configs.SetAuditLog(s.audit)
+	specifics, err := NewSpecificConfigService(specificDir, opts)
+	if err != nil {
+		return fmt.Errorf("environment %s: %w", env.Name, err)
+	}
+	env.Configs, env.Specifics = configs, specifics
+	return nil
+}
+
+// reload reads every environment but the served one again from disk.
+// Callers must hold the mutex.
+func (s *EnvironmentService) reload() error {
+	for i, env := range s.environments {
+		if env.Name == s.served {
+			continue
+		}
+		if err := s.load(env, i == len(s.environments)-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseEnvironments reads a comma-separated promotion chain such as
+// "dev,staging,prod".
+func ParseEnvironments(value string) []string {
+	return splitList(value)
+}
+
+// EnvironmentDirs returns the config and specific config directories of an
+// environment.
+func EnvironmentDirs(name string, production bool) (string, string) {
+	if production {
+		return "config_files", "specific_configs"
+	}
+	base := filepath.Join("environments", name)
+	return filepath.Join(base, "config_files"), filepath.Join(base, "specific_configs")
+}
+
+func (s *EnvironmentService) Get(name string) (*Environment, error) {
+	for _, env := range s.environments {
+		if env.Name == name {
+			return env, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown environment '%s'", name)
+}
+
+// Served returns the environment this server matches against.
+func (s *EnvironmentService) Served() *Environment {
+	env, _ := s.Get(s.served)
+	return env
+}
+
+// next returns the environment after name in the promotion chain.
+func (s *EnvironmentService) next(name string) (*Environment, error) {
+	for i, env := range s.environments {
+		if env.Name == name {
+			if i == len(s.environments)-1 {
+				return nil, fmt.Errorf("'%s' is the last environment", name)
+			}
+			return s.environments[i+1], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown environment '%s'", name)
+}
+
+// LoadReport merges the load reports of every environment.
+func (s *EnvironmentService) LoadReport() models.LoadReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := models.LoadReport{Failed: []models.LoadDiagnostic{}, Warnings: []models.LoadDiagnostic{}}
+	for _, env := range s.environments {
+		for _, part := range []models.LoadReport{env.Configs.LoadReport(), env.Specifics.LoadReport()} {
+			report.Mode = part.Mode
+			if report.LoadedAt.IsZero() || part.LoadedAt.Before(report.LoadedAt) {
+				report.LoadedAt = part.LoadedAt
+			}
+			report.Loaded += part.Loaded
+			for _, diagnostic := range part.Failed {
+				diagnostic.Environment = env.Name
+				report.Failed = append(report.Failed, diagnostic)
+			}
+			for _, diagnostic := range part.Warnings {
+				diagnostic.Environment = env.Name
+				report.Warnings = append(report.Warnings, diagnostic)
+			}
+		}
+	}
+	return report
+}
+
+// Readiness reports whether the served environment loaded completely. The
+// server is degraded, but still serving, when files were skipped.
+func (s *EnvironmentService) Readiness() models.ReadinessStatus {
+	status := models.ReadinessStatus{Status: "ready", Environment: s.served}
+	for _, diagnostic := range s.LoadReport().Failed {
+		if diagnostic.Environment == s.served {
+			status.FailedFiles++
+		}
+	}
+	if status.FailedFiles > 0 {
+		status.Status = "degraded"
+	}
+	return status
+}
+
+func (s *EnvironmentService) Summaries() []models.EnvironmentSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	summaries := make([]models.EnvironmentSummary, len(s.environments))
+	for i, env := range s.environments {
+		configs, _ := env.Configs.GetAllConfigs()
+		specifics, _ := env.Specifics.GetAllSpecificConfigs()
+		summaries[i] = models.EnvironmentSummary{
+			Name:            env.Name,
+			Served:          env.Name == s.served,
+			Configs:         len(configs),
+			SpecificConfigs: len(specifics),
+		}
+	}
+	return summaries
+}
+
+// Promote copies the published revisions of the selected documents from one
+// environment to the next. Both are read again from disk and every document
+// is checked before anything is written; with DryRun only the planned
+// changes are returned. The promotion is applied all or nothing: when a
+// write fails the ones before it are undone.
+func (s *EnvironmentService) Promote(user string, req models.PromoteRequest) (models.PromoteResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.reload(); err != nil {
+		return models.PromoteResult{}, err
+	}
+	from, err := s.Get(req.From)
+	if err != nil {
+		return models.PromoteResult{}, err
+	}
+	to, err := s.next(req.From)
+	if err != nil {
+		return models.PromoteResult{}, err
+	}
+	if req.To != "" && req.To != to.Name {
+		return models.PromoteResult{}, fmt.Errorf("'%s' can only be promoted to '%s'", from.Name, to.Name)
+	}
+	if len(req.Configs) == 0 && len(req.SpecificConfigs) == 0 {
+		return models.PromoteResult{}, errors.New("select at least one config or specific config")
+	}
+
+	result := models.PromoteResult{From: from.Name, To: to.Name, DryRun: req.DryRun, Changes: []models.PromotionChange{}}
+	var steps []documentStep
+	plan := func(change models.PromotionChange, step documentStep) {
+		result.Changes = append(result.Changes, change)
+		if change.Action != "unchanged" {
+			step.exists = change.Action == "update"
+			steps = append(steps, step)
+		}
+	}
+	for _, id := range req.Configs {
+		config, err := from.Configs.GetConfigByID(id)
+		if err != nil {
+			return models.PromoteResult{}, fmt.Errorf("config '%s' is not published in %s", id, from.Name)
+		}
+		current, err := to.Configs.GetConfigByID(id)
+		plan(planChange(models.ChangeKindConfig, id, configYAML(current, err == nil), configYAML(config, true)),
+			documentStep{kind: models.ChangeKindConfig, id: id, config: config, previousConfig: current})
+	}
+	for _, id := range req.SpecificConfigs {
+		config, err := from.Specifics.GetSpecificConfigByID(id)
+		if err != nil {
+			return models.PromoteResult{}, fmt.Errorf("specific config '%s' not found in %s", id, from.Name)
+		}
+		if err := to.Specifics.ValidateSpecificConfig(config); err != nil {
+			return models.PromoteResult{}, fmt.Errorf("specific config '%s' is invalid in %s: %w", id, to.Name, err)
+		}
+		current, err := to.Specifics.GetSpecificConfigByID(id)
+		plan(planChange(models.ChangeKindSpecific, id, specificYAML(current, err == nil), specificYAML(config, true)),
+			documentStep{kind: models.ChangeKindSpecific, id: id, specific: config, previousSpecific: current})
+	}
+	if req.DryRun {
+		return result, nil
+	}
+
+	for i, step := range steps {
+		if err := step.apply(to.Configs, to.Specifics); err != nil {
+			err = fmt.Errorf("%s '%s' could not be promoted: %w", step.kind, step.id, err)
+			var failed []string
+			for j := i - 1; j >= 0; j-- {
+				if rollbackErr := steps[j].rollback(to.Configs, to.Specifics); rollbackErr != nil {
+					failed = append(failed, fmt.Sprintf("%s '%s': %v", steps[j].kind, steps[j].id, rollbackErr))
+				}
+			}
+			if len(failed) > 0 {
+				return models.PromoteResult{}, fmt.Errorf("%w; %s could not be restored: %s", err, to.Name, strings.Join(failed, "; "))
+			}
+			return models.PromoteResult{}, fmt.Errorf("%w; nothing was changed", err)
+		}
+	}
+	if s.audit != nil {
+		for _, step := range steps {
+			if err := s.audit.Record(user, "env.promote", step.kind+":"+step.id, from.Name+" -> "+to.Name); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func planChange(kind, id, before, after string) models.PromotionChange {
+	change := models.PromotionChange{Kind: kind, ID: id, Action: "update"}
+	if before == "" {
+		change.Action = "create"
+	}
+	lines, changed := diffLines(before, after)
+	if !changed {
+		change.Action = "unchanged"
+		return change
+	}
+	change.Diff = lines
+	return change
+}
+
+// Drift compares every config and specific config across all environments,
+// as they are on disk, and reports the ones that are missing somewhere or
+// differ.
+func (s *EnvironmentService) Drift() (models.DriftReport, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.reload(); err != nil {
+		return models.DriftReport{}, err
+	}
+	report := models.DriftReport{Drift: []models.DriftEntry{}}
+	revisions := map[string]map[string]string{} // kind:id -> environment -> checksum
+	add := func(kind, id, env, document string) {
+		key := kind + ":" + id
+		if revisions[key] == nil {
+			revisions[key] = map[string]string{}
+		}
+		revisions[key][env] = checksum(document)
+	}
+
+	for _, env := range s.environments {
+		report.Environments = append(report.Environments, env.Name)
+		configs, _ := env.Configs.GetAllConfigs()
+		for _, config := range configs {
+			add(models.ChangeKindConfig, config.ID, env.Name, configYAML(config, true))
+		}
+		specifics, _ := env.Specifics.GetAllSpecificConfigs()
+		for _, config := range specifics {
+			add(models.ChangeKindSpecific, config.ID, env.Name, specificYAML(config, true))
+		}
+	}
+
+	for key, byEnv := range revisions {
+		first, inSync := "", true
+		for i, env := range report.Environments {
+			if i == 0 {
+				first = byEnv[env]
+			}
+			if byEnv[env] == "" || byEnv[env] != first {
+				inSync = false
+			}
+		}
+		if inSync {
+			continue
+		}
+
+		kind, id, _ := strings.Cut(key, ":")
+		entry := models.DriftEntry{Kind: kind, ID: id, Revisions: map[string]string{}}
+		for _, env := range report.Environments {
+			entry.Revisions[env] = byEnv[env]
+		}
+		report.Drift = append(report.Drift, entry)
+	}
+	sort.Slice(report.Drift, func(i, j int) bool {
+		if report.Drift[i].Kind != report.Drift[j].Kind {
+			return report.Drift[i].Kind < report.Drift[j].Kind
+		}
+		return report.Drift[i].ID < report.Drift[j].ID
+	})
+	report.InSync = len(report.Drift) == 0
+	return report, nil
+}
+
+func configYAML(config models.Config, exists bool) string {
+	if !exists {
+		return ""
+	}
+	config = config.WithoutTimestamps()
+	data, _ := yamlv2.Marshal(&config)
+	return string(data)
+}
+
+func specificYAML(config models.SpecificConfig, exists bool) string {
+	if !exists {
+		return ""
+	}
+	config = config.WithoutTimestamps()
+	data, _ := yaml.Marshal(&config)
+	return string(data)
+}
+
+func checksum(document string) string {
+	sum := sha256.Sum256([]byte(document))
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"os"
+	"ssd-assignment-api/models"
+	"testing"
+)
+
+// newTestEnvironments starts dev and prod in an empty working directory
+// holding only the production directories.
+func newTestEnvironments(t *testing.T) *EnvironmentService {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	for _, dir := range []string{"config_files", "specific_configs"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	audit, err := NewAuditLog("state/audit.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	environments, err := NewEnvironmentService([]string{"dev", "prod"}, "prod", LoadOptions{}, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return environments
+}
+
+func TestPromoteReadsSourceFromDisk(t *testing.T) {
+	environments := newTestEnvironments(t)
+	if _, err := os.Stat("environments"); !os.IsNotExist(err) {
+		t.Fatalf("startup created the environment directories: %v", err)
+	}
+
+	// dev is written by another server after this one started
+	if err := os.MkdirAll("environments/dev/config_files", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("environments/dev/config_files/A.yaml", []byte("id: A\nactions: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	drift, err := environments.Drift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift.InSync {
+		t.Error("drift does not see the config written to dev")
+	}
+	if _, err := environments.Promote("alice", models.PromoteRequest{From: "dev", Configs: []string{"A"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := environments.Served().Configs.GetConfigByID("A"); err != nil {
+		t.Error("promoted config is not served")
+	}
+}
+
+func TestPromoteIsAllOrNothing(t *testing.T) {
+	environments := newTestEnvironments(t)
+	dev, _ := environments.Get("dev")
+	if err := dev.Configs.AddConfig(models.Config{ID: "A", Actions: []models.Action{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Specifics.AddSpecificConfig(models.SpecificConfig{ID: "S", DataSource: models.DataSource{
+		Hosts: map[string]models.Mapping{"example.com": ids("A")},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in place of the file makes writing the specific config fail
+	if err := os.Mkdir("specific_configs/S.yaml", 0755); err != nil {
+		t.Fatal(err)
+	}
+	_, err := environments.Promote("alice", models.PromoteRequest{From: "dev", Configs: []string{"A"}, SpecificConfigs: []string{"S"}})
+	if err == nil {
+		t.Fatal("promotion succeeded although a write failed")
+	}
+	if _, err := environments.Served().Configs.GetConfigByID("A"); err == nil {
+		t.Error("config written before the failure was not rolled back")
+	}
+	if _, err := os.Stat("config_files/A.yaml"); !os.IsNotExist(err) {
+		t.Errorf("file of the rolled back config is left: %v", err)
+	}
+}
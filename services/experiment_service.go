@@ -0,0 +1,301 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExperimentRefPrefix marks a mapping ID that refers to an experiment rather
+// than a configuration, e.g. "experiment:headline".
+const ExperimentRefPrefix = "experiment:"
+
+// experimentBuckets is the resolution visitors are bucketed at; holdout
+// percentages and weights are mapped onto it.
+const experimentBuckets = 10000
+
+type ExperimentService struct {
+	experiments map[string]models.Experiment
+	mutex       sync.Mutex
+	yamlDir     string
+
+	// serving is a copy of experiments for Assign, which runs on every match
+	// and must not wait for writers doing file I/O under the mutex.
+	serving atomic.Pointer[map[string]models.Experiment]
+}
+
+func NewExperimentService(yamlDir string) (*ExperimentService, error) {
+	service := &ExperimentService{
+		experiments: make(map[string]models.Experiment),
+		yamlDir:     yamlDir,
+	}
+
+	if err := service.loadExperimentsFromYAML(); err != nil {
+		return nil, fmt.Errorf("failed to load experiments: %w", err)
+	}
+
+	return service, nil
+}
+
+func (s *ExperimentService) loadExperimentsFromYAML() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(s.yamlDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	files, err := os.ReadDir(s.yamlDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
+			continue
+		}
+
+		filePath := filepath.Join(s.yamlDir, file.Name())
+		yamlData, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+
+		var experiment models.Experiment
+		if err := yaml.Unmarshal(yamlData, &experiment); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+		if err := validateExperiment(experiment); err != nil {
+			return fmt.Errorf("invalid experiment in %s: %w", filePath, err)
+		}
+
+		s.experiments[experiment.ID] = experiment
+	}
+	s.publishServing()
+	return nil
+}
+
+// publishServing replaces the experiments Assign reads. Callers must hold
+// the mutex.
+func (s *ExperimentService) publishServing() {
+	experiments := make(map[string]models.Experiment, len(s.experiments))
+	for id, experiment := range s.experiments {
+		experiments[id] = experiment
+	}
+	s.serving.Store(&experiments)
+}
+
+func validateExperiment(experiment models.Experiment) error {
+	if experiment.ID == "" {
+		return errors.New("experiment ID is required")
+	}
+	if err := checkFileName(experiment.ID); err != nil {
+		return err
+	}
+	if experiment.Holdout < 0 || experiment.Holdout > 100 {
+		return errors.New("holdout must be between 0 and 100")
+	}
+	if len(experiment.Variants) == 0 {
+		return errors.New("at least one variant is required")
+	}
+
+	total := 0
+	names := make(map[string]bool, len(experiment.Variants))
+	for i, variant := range experiment.Variants {
+		if variant.Name == "" {
+			return fmt.Errorf("variant %d: name is required", i)
+		}
+		if names[variant.Name] {
+			return fmt.Errorf("variant '%s' is defined twice", variant.Name)
+		}
+		names[variant.Name] = true
+		if variant.Weight < 0 {
+			return fmt.Errorf("variant '%s': weight must not be negative", variant.Name)
+		}
+		for _, id := range variant.IDs {
+			if strings.HasPrefix(id, ExperimentRefPrefix) {
+				return fmt.Errorf("variant '%s': experiments can not be nested", variant.Name)
+			}
+		}
+		total += variant.Weight
+	}
+	if total == 0 {
+		return errors.New("at least one variant needs a positive weight")
+	}
+	return nil
+}
+
+// Assign implements VariantAssigner. It buckets the visitor by hashing the
+// visitor ID with the experiment salt, so a visitor keeps its variant for as
+// long as the salt, holdout and weights do not change.
+func (s *ExperimentService) Assign(ref string, req MatchRequest) (models.ExperimentAssignment, bool) {
+	id, ok := strings.CutPrefix(ref, ExperimentRefPrefix)
+	if !ok {
+		return models.ExperimentAssignment{}, false
+	}
+
+	experiment, exists := (*s.serving.Load())[id]
+
+	assignment := models.ExperimentAssignment{Experiment: id}
+	switch {
+	case !exists:
+		assignment.Reason = "experiment not found"
+		return assignment, true
+	case req.VisitorID == "":
+		assignment.Holdout = true
+		assignment.Reason = "no visitor ID"
+		return assignment, true
+	}
+
+	bucket := experimentBucket(experiment, req.VisitorID)
+	holdout := experiment.Holdout * experimentBuckets / 100
+	if bucket < holdout {
+		assignment.Holdout = true
+		return assignment, true
+	}
+
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+	// Spread the buckets outside the holdout over the variants by weight
+	position := (bucket - holdout) * total / (experimentBuckets - holdout)
+	for _, variant := range experiment.Variants {
+		if position < variant.Weight {
+			assignment.Variant = variant.Name
+			assignment.ConfigIDs = variant.IDs
+			break
+		}
+		position -= variant.Weight
+	}
+	return assignment, true
+}
+
+func experimentBucket(experiment models.Experiment, visitorID string) int {
+	salt := experiment.Salt
+	if salt == "" {
+		salt = experiment.ID
+	}
+	return visitorBucket(salt, visitorID, experimentBuckets)
+}
+
+// visitorBucket deterministically maps a visitor to one of n buckets; salt
+// keeps the buckets of different experiments and rollouts independent.
+func visitorBucket(salt, visitorID string, n int) int {
+	h := fnv.New64a()
+	h.Write([]byte(salt + ":" + visitorID))
+	return int(h.Sum64() % uint64(n))
+}
+
+func (s *ExperimentService) GetAllExperiments() ([]models.Experiment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	experimentList := make([]models.Experiment, 0, len(s.experiments))
+	for _, experiment := range s.experiments {
+		experimentList = append(experimentList, experiment)
+	}
+	sort.Slice(experimentList, func(i, j int) bool {
+		return experimentList[i].ID < experimentList[j].ID
+	})
+	return experimentList, nil
+}
+
+func (s *ExperimentService) GetExperimentByID(id string) (models.Experiment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	experiment, exists := s.experiments[id]
+	if !exists {
+		return models.Experiment{}, errors.New("experiment not found")
+	}
+	return experiment, nil
+}
+
+func (s *ExperimentService) AddExperiment(experiment models.Experiment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.experiments[experiment.ID]; exists {
+		return fmt.Errorf("experiment with ID '%s' already exists", experiment.ID)
+	}
+	if err := validateExperiment(experiment); err != nil {
+		return err
+	}
+
+	if err := s.saveExperimentToYAML(experiment); err != nil {
+		return fmt.Errorf("failed to save experiment: %w", err)
+	}
+
+	s.experiments[experiment.ID] = experiment
+	s.publishServing()
+	return nil
+}
+
+func (s *ExperimentService) UpdateExperiment(id string, experiment models.Experiment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.experiments[id]; !exists {
+		return errors.New("experiment not found")
+	}
+	experiment.ID = id
+	if err := validateExperiment(experiment); err != nil {
+		return err
+	}
+
+	if err := s.saveExperimentToYAML(experiment); err != nil {
+		return fmt.Errorf("failed to update experiment: %w", err)
+	}
+
+	s.experiments[id] = experiment
+	s.publishServing()
+	return nil
+}
+
+func (s *ExperimentService) DeleteExperiment(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.experiments[id]; !exists {
+		return errors.New("experiment not found")
+	}
+
+	if err := checkFileName(id); err != nil {
+		return err
+	}
+	filePath := filepath.Join(s.yamlDir, id+".yaml")
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to delete experiment file: %w", err)
+	}
+
+	delete(s.experiments, id)
+	s.publishServing()
+	return nil
+}
+
+func (s *ExperimentService) saveExperimentToYAML(experiment models.Experiment) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(experiment); err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	encoder.Close()
+
+	filePath := filepath.Join(s.yamlDir, experiment.ID+".yaml")
+	if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
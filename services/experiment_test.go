@@ -0,0 +1,46 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"ssd-assignment-api/models"
+	"testing"
+)
+
+func TestExperimentService(t *testing.T) {
+	dir := t.TempDir()
+	experiments, err := NewExperimentService(filepath.Join(dir, "experiments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	variants := []models.Variant{{Name: "a", IDs: []string{"A"}, Weight: 1}}
+
+	for _, id := range []string{"c", "a", "b"} {
+		if err := experiments.AddExperiment(models.Experiment{ID: id, Variants: variants}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, _ := experiments.GetAllExperiments()
+	if len(all) != 3 || all[0].ID != "a" || all[1].ID != "b" || all[2].ID != "c" {
+		t.Errorf("experiments in order %+v", all)
+	}
+
+	if err := experiments.AddExperiment(models.Experiment{ID: "../evil", Variants: variants}); err == nil {
+		t.Error("an experiment ID outside the directory was accepted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.yaml")); !os.IsNotExist(err) {
+		t.Error("a file was written outside the experiments directory")
+	}
+
+	// Assign sees writes once they are published
+	assignment, _ := experiments.Assign(ExperimentRefPrefix+"a", MatchRequest{VisitorID: "v"})
+	if assignment.Variant != "a" {
+		t.Errorf("assigned %+v", assignment)
+	}
+	if err := experiments.DeleteExperiment("a"); err != nil {
+		t.Fatal(err)
+	}
+	if assignment, _ := experiments.Assign(ExperimentRefPrefix+"a", MatchRequest{VisitorID: "v"}); assignment.Reason != "experiment not found" {
+		t.Errorf("a deleted experiment assigned %+v", assignment)
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"ssd-assignment-api/models"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kill switch scopes.
+const (
+	KillScopeGlobal = "global"
+	KillScopeHost   = "host"
+	KillScopeConfig = "config"
+)
+
+// KillSwitchService stops configs from being served without deleting them.
+// The state is persisted so a switch stays on across restarts.
+type KillSwitchService struct {
+	switches  atomic.Pointer[killSwitches]
+	normalize NormalizeOptions // the options of the match index, so killed hosts compare with request hosts
+	audit     *AuditLog
+	mutex     sync.Mutex // serializes toggles; Allow reads switches without it
+	path      string
+}
+
+// killSwitches is the state of all switches. It is never modified once
+// published; a toggle publishes a changed copy.
+type killSwitches struct {
+	global  bool
+	hosts   map[string]bool
+	configs map[string]bool
+}
+
+// NewKillSwitchService loads the persisted switches. Hosts are normalized
+// with opts, which must be the options the specific configs are matched with.
+func NewKillSwitchService(path string, opts NormalizeOptions, audit *AuditLog) (*KillSwitchService, error) {
+	service := &KillSwitchService{
+		normalize: opts,
+		audit:     audit,
+		path:      path,
+	}
+	switches := &killSwitches{
+		hosts:   make(map[string]bool),
+		configs: make(map[string]bool),
+	}
+	service.switches.Store(switches)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return service, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kill switches: %w", err)
+	}
+
+	var state models.KillSwitches
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	switches.global = state.Global
+	for _, host := range state.Hosts {
+		switches.hosts[NormalizeHost(host, opts)] = true
+	}
+	for _, id := range state.Configs {
+		switches.configs[ConfigIDFromRef(id)] = true
+	}
+
+	return service, nil
+}
+
+// State returns the switches that are currently on.
+func (s *KillSwitchService) State() models.KillSwitches {
+	return s.switches.Load().state()
+}
+
+func (k *killSwitches) state() models.KillSwitches {
+	return models.KillSwitches{Global: k.global, Hosts: sortedKeys(k.hosts), Configs: sortedKeys(k.configs)}
+}
+
+func (k *killSwitches) clone() *killSwitches {
+	clone := &killSwitches{
+		global:  k.global,
+		hosts:   make(map[string]bool, len(k.hosts)),
+		configs: make(map[string]bool, len(k.configs)),
+	}
+	for host := range k.hosts {
+		clone.hosts[host] = true
+	}
+	for id := range k.configs {
+		clone.configs[id] = true
+	}
+	return clone
+}
+
+// Toggle turns a switch on or off, persists the new state and records the
+// change in the audit log. The new state is only published once it is saved.
+func (s *KillSwitchService) Toggle(user string, toggle models.KillSwitchToggle) (models.KillSwitches, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switches := s.switches.Load().clone()
+	target := toggle.Target
+	switch toggle.Scope {
+	case KillScopeGlobal:
+		target = ""
+		switches.global = toggle.Killed
+	case KillScopeHost:
+		target = NormalizeHost(target, s.normalize)
+		if target == "" {
+			return models.KillSwitches{}, errors.New("target host is required")
+		}
+		setSwitch(switches.hosts, target, toggle.Killed)
+	case KillScopeConfig:
+		target = ConfigIDFromRef(target)
+		if target == "" {
+			return models.KillSwitches{}, errors.New("target config ID is required")
+		}
+		setSwitch(switches.configs, target, toggle.Killed)
+	default:
+		return models.KillSwitches{}, fmt.Errorf("unknown kill switch scope '%s'", toggle.Scope)
+	}
+
+	state := switches.state()
+	if err := s.save(state); err != nil {
+		return models.KillSwitches{}, err
+	}
+	s.switches.Store(switches)
+
+	action := "killswitch.off"
+	if toggle.Killed {
+		action = "killswitch.on"
+	}
+	if err := s.audit.Record(user, action, toggle.Scope+":"+target, toggle.Reason); err != nil {
+		return state, fmt.Errorf("kill switch changed but could not be audited: %w", err)
+	}
+	return state, nil
+}
+
+// Allow implements ConfigFilter. It reads the published switches without
+// locking.
+func (s *KillSwitchService) Allow(ref string, req MatchRequest) (bool, string) {
+	switches := s.switches.Load()
+
+	switch {
+	case switches.global:
+		return false, "global kill switch is on"
+	case req.Host != "" && switches.hosts[req.Host]:
+		return false, "kill switch is on for host " + req.Host
+	case switches.configs[ConfigIDFromRef(ref)]:
+		return false, "kill switch is on for the config"
+	}
+	return true, ""
+}
+
+func (s *KillSwitchService) save(state models.KillSwitches) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(state); err != nil {
+		return fmt.Errorf("failed to marshal kill switches: %w", err)
+	}
+	encoder.Close()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write kill switches: %w", err)
+	}
+	return nil
+}
+
+func setSwitch(switches map[string]bool, key string, on bool) {
+	if on {
+		switches[key] = true
+	} else {
+		delete(switches, key)
+	}
+}
+
+func sortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
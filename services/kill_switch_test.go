@@ -0,0 +1,42 @@
+package services
+
+import (
+	"ssd-assignment-api/models"
+	"testing"
+)
+
+func TestKillSwitchHostUsesMatchNormalization(t *testing.T) {
+	opts := NormalizeOptions{StripWWW: true, StripPort: true}
+	dir := t.TempDir()
+	audit, err := NewAuditLog(dir + "/audit.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	switches, err := NewKillSwitchService(dir+"/kill_switches.yaml", opts, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestSpecificService(t, opts, models.SpecificConfig{ID: "s", DataSource: models.DataSource{
+		Hosts: map[string]models.Mapping{"www.example.com": ids("A")},
+	}})
+	s.AddConfigFilter(switches)
+
+	if _, err := switches.Toggle("alice", models.KillSwitchToggle{Scope: KillScopeHost, Target: "WWW.example.com:443", Killed: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"www.example.com", "example.com", "example.com:8080"} {
+		if got := s.GetMatchingConfigs(MatchRequest{Host: host}).ConfigIDs; len(got) != 0 {
+			t.Errorf("host %s matched %v with the kill switch on", host, got)
+		}
+	}
+
+	// The switch survives a restart with the same normalization
+	reloaded, err := NewKillSwitchService(dir+"/kill_switches.yaml", opts, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := reloaded.Allow("A", MatchRequest{Host: "example.com"}); ok {
+		t.Error("reloaded kill switch allows the host")
+	}
+}
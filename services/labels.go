@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LabelSelector filters configurations by their labels. It is parsed from
+// the Kubernetes selector syntax: comma separated requirements that must all
+// hold, such as "team=growth,brand in (x,y),!deprecated".
+type LabelSelector []labelRequirement
+
+// Selector operators.
+const (
+	selectEquals    = "="
+	selectNotEquals = "!="
+	selectIn        = "in"
+	selectNotIn     = "notin"
+	selectExists    = "exists"
+	selectNotExists = "!"
+)
+
+type labelRequirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+// labelName is the syntax of label keys and values: letters, digits and
+// "-", "_", "." and "/".
+var labelName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+var setRequirement = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// ParseLabelSelector parses a selector; an empty selector matches
+// everything.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var parsed LabelSelector
+	for _, part := range splitSelector(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid label selector '%s': empty requirement", selector)
+		}
+
+		var req labelRequirement
+		if match := setRequirement.FindStringSubmatch(part); match != nil {
+			req = labelRequirement{key: match[1], operator: match[2]}
+			for _, value := range strings.Split(match[3], ",") {
+				req.values = append(req.values, strings.TrimSpace(value))
+			}
+		} else if key, value, ok := strings.Cut(part, "!="); ok {
+			req = labelRequirement{key: key, operator: selectNotEquals, values: []string{value}}
+		} else if key, value, ok := strings.Cut(part, "=="); ok {
+			req = labelRequirement{key: key, operator: selectEquals, values: []string{value}}
+		} else if key, value, ok := strings.Cut(part, "="); ok {
+			req = labelRequirement{key: key, operator: selectEquals, values: []string{value}}
+		} else if key, ok := strings.CutPrefix(part, "!"); ok {
+			req = labelRequirement{key: key, operator: selectNotExists}
+		} else {
+			req = labelRequirement{key: part, operator: selectExists}
+		}
+
+		req.key = strings.TrimSpace(req.key)
+		if !labelName.MatchString(req.key) {
+			return nil, fmt.Errorf("invalid label selector '%s': invalid key '%s'", selector, req.key)
+		}
+		for i, value := range req.values {
+			req.values[i] = strings.TrimSpace(value)
+			if req.values[i] != "" && !labelName.MatchString(req.values[i]) {
+				return nil, fmt.Errorf("invalid label selector '%s': invalid value '%s'", selector, req.values[i])
+			}
+		}
+		parsed = append(parsed, req)
+	}
+	return parsed, nil
+}
+
+// splitSelector splits a selector on the commas outside of parentheses.
+func splitSelector(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+// Matches reports whether labels satisfy every requirement.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		value, exists := labels[req.key]
+		var ok bool
+		switch req.operator {
+		case selectEquals, selectIn:
+			ok = exists && containsString(req.values, value)
+		case selectNotEquals, selectNotIn:
+			ok = !exists || !containsString(req.values, value)
+		case selectExists:
+			ok = exists
+		case selectNotExists:
+			ok = !exists
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// validateLabels checks that labels can be selected on.
+func validateLabels(labels map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		if !labelName.MatchString(key) {
+			return fmt.Errorf("invalid label key '%s'", key)
+		}
+		if value := labels[key]; value != "" && !labelName.MatchString(value) {
+			return fmt.Errorf("invalid value '%s' of label '%s'", value, key)
+		}
+	}
+	return nil
+}
+
+// labelIndex maps label keys and values to the IDs carrying them, so a
+// selector only has to look at the IDs its equality and set requirements
+// name.
+type labelIndex map[string]map[string]map[string]bool
+
+func (x labelIndex) add(id string, labels map[string]string) {
+	for key, value := range labels {
+		if x[key] == nil {
+			x[key] = map[string]map[string]bool{}
+		}
+		if x[key][value] == nil {
+			x[key][value] = map[string]bool{}
+		}
+		x[key][value][id] = true
+	}
+}
+
+func (x labelIndex) remove(id string, labels map[string]string) {
+	for key, value := range labels {
+		delete(x[key][value], id)
+		if len(x[key][value]) == 0 {
+			delete(x[key], value)
+		}
+		if len(x[key]) == 0 {
+			delete(x, key)
+		}
+	}
+}
+
+// candidates returns the IDs that can match a selector, or all when the
+// selector has no requirement the index can answer.
+func (x labelIndex) candidates(selector LabelSelector, all []string) []string {
+	var ids map[string]bool
+	for _, req := range selector {
+		if req.operator != selectEquals && req.operator != selectIn {
+			continue
+		}
+		matching := map[string]bool{}
+		for _, value := range req.values {
+			for id := range x[req.key][value] {
+				if ids == nil || ids[id] {
+					matching[id] = true
+				}
+			}
+		}
+		ids = matching
+	}
+	if ids == nil {
+		return all
+	}
+
+	found := make([]string, 0, len(ids))
+	for id := range ids {
+		found = append(found, id)
+	}
+	sort.Strings(found)
+	return found
+}
@@ -0,0 +1,468 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"ssd-assignment-api/models"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintOptions selects the directories Lint checks. Any of them may be empty;
+// references are only checked against the directories that are given.
+type LintOptions struct {
+	ConfigDir     string
+	SpecificDir   string
+	ExperimentDir string
+	Normalize     NormalizeOptions
+}
+
+// Lint parses the YAML files of the directories the same way the services
+// load them and runs every validation on them, reporting each problem with
+// the file and line it was found at. It only fails when a directory can not
+// be read.
+func Lint(opts LintOptions) (models.LintReport, error) {
+	l := &linter{
+		opts:        opts,
+		configs:     map[string]string{},
+		specifics:   map[string]string{},
+		experiments: map[string]string{},
+	}
+
+	configFiles, err := l.read(opts.ConfigDir, documentExtensions)
+	if err != nil {
+		return models.LintReport{}, err
+	}
+	specificFiles, err := l.read(opts.SpecificDir, documentExtensions)
+	if err != nil {
+		return models.LintReport{}, err
+	}
+	experimentFiles, err := l.read(opts.ExperimentDir, []string{".yaml"})
+	if err != nil {
+		return models.LintReport{}, err
+	}
+
+	// Every ID is collected before references are checked
+	var specifics []lintSpecific
+	var experiments []lintExperiment
+	for _, file := range configFiles {
+		l.lintConfig(file)
+	}
+	for _, file := range experimentFiles {
+		if experiment, ok := l.lintExperiment(file); ok {
+			experiments = append(experiments, experiment)
+		}
+	}
+	for _, file := range specificFiles {
+		if config, ok := l.lintSpecific(file); ok {
+			specifics = append(specifics, config)
+		}
+	}
+
+	for _, experiment := range experiments {
+		for i, variant := range experiment.Variants {
+			for j, ref := range variant.IDs {
+				l.checkRef(experiment.file, []string{"variants", strconv.Itoa(i), "ids", strconv.Itoa(j)}, ref)
+			}
+		}
+	}
+	for _, config := range specifics {
+		for _, ref := range specificRefs(config.SpecificConfig) {
+			l.checkRef(config.file, ref.path, ref.id)
+		}
+	}
+
+	sort.SliceStable(l.report.Issues, func(i, j int) bool {
+		a, b := l.report.Issues[i], l.report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return l.report, nil
+}
+
+type linter struct {
+	opts   LintOptions
+	report models.LintReport
+
+	// IDs mapped to the file they were first defined in
+	configs     map[string]string
+	specifics   map[string]string
+	experiments map[string]string
+}
+
+// lintFile is a document of a YAML or JSON file together with its node
+// tree, which is nil when the file does not parse. Line numbers of the tree
+// count from the start of the file.
+type lintFile struct {
+	path     string
+	root     *yaml.Node
+	single   bool // the file holds no other document
+	resource bool // the document is in the resource form
+}
+
+type lintSpecific struct {
+	models.SpecificConfig
+	file lintFile
+}
+
+type lintExperiment struct {
+	models.Experiment
+	file lintFile
+}
+
+// read loads the documents of the files of a directory that have one of the
+// extensions the service reading the directory loads. A file that does not
+// parse is a single lintFile without a tree.
+func (l *linter) read(dir string, extensions []string) ([]lintFile, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("directory %s could not be read: %w", dir, err)
+	}
+
+	var files []lintFile
+	for _, entry := range entries {
+		if entry.IsDir() || !containsString(extensions, filepath.Ext(entry.Name())) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		docs, err := readDocuments(path)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, fmt.Errorf("%s could not be read: %w", path, err)
+		}
+		l.report.Files = append(l.report.Files, path)
+
+		if err != nil {
+			file := lintFile{path: path, single: true}
+			l.parseError(file, err)
+			files = append(files, file)
+			continue
+		}
+		for _, doc := range docs {
+			if doc != nil {
+				files = append(files, lintFile{path: path, root: doc, single: len(docs) == 1, resource: isResource(doc)})
+			}
+		}
+	}
+	return files, nil
+}
+
+func (l *linter) lintConfig(file lintFile) {
+	if file.root == nil {
+		return
+	}
+	config, err := decodeConfigDocument(file.root)
+	if err != nil {
+		l.parseError(file, err)
+		return
+	}
+
+	l.checkID(file, config.ID, l.configs)
+	if len(config.Actions) == 0 {
+		l.add(file, []string{"actions"}, models.SeverityWarning, "schema", "config has no actions")
+	}
+	if _, err := scheduleWindow(config); err != nil {
+		l.add(file, schedulePath(config.Schedule), models.SeverityError, "schema", err.Error())
+	}
+	if err := validateRollout(config.Rollout); err != nil {
+		l.add(file, []string{"rollout"}, models.SeverityError, "schema", err.Error())
+	}
+	if err := validateLabels(config.Labels); err != nil {
+		l.add(file, []string{"labels"}, models.SeverityError, "schema", err.Error())
+	}
+	for _, problem := range validateActions(config.Actions) {
+		l.add(file, problem.Path, models.SeverityError, problem.Rule, problem.Message)
+	}
+}
+
+func (l *linter) lintExperiment(file lintFile) (lintExperiment, bool) {
+	if file.root == nil {
+		return lintExperiment{}, false
+	}
+	var experiment models.Experiment
+	if err := file.root.Decode(&experiment); err != nil {
+		l.parseError(file, err)
+		return lintExperiment{}, false
+	}
+
+	l.checkID(file, experiment.ID, l.experiments)
+	if err := validateExperiment(experiment); err != nil {
+		l.add(file, nil, models.SeverityError, "schema", err.Error())
+	}
+	return lintExperiment{experiment, file}, true
+}
+
+func (l *linter) lintSpecific(file lintFile) (lintSpecific, bool) {
+	if file.root == nil {
+		return lintSpecific{}, false
+	}
+	config, err := decodeSpecificDocument(file.root)
+	if err != nil {
+		l.parseError(file, err)
+		return lintSpecific{}, false
+	}
+
+	l.checkID(file, config.ID, l.specifics)
+	if err := validateLabels(config.Labels); err != nil {
+		l.add(file, []string{"labels"}, models.SeverityError, "schema", err.Error())
+	}
+
+	// Every part is compiled on its own so a bad pattern can be located
+	for _, part := range specificParts(config) {
+		if _, err := compileSpecificConfig(part.config, l.opts.Normalize); err != nil {
+			l.add(file, part.path, models.SeverityError, "pattern", err.Error())
+		}
+	}
+	return lintSpecific{config, file}, true
+}
+
+// checkID reports a missing ID, an ID that does not match the name of a
+// single-document file and an ID that another document of the same kind
+// already defines.
+func (l *linter) checkID(file lintFile, id string, seen map[string]string) {
+	if id == "" {
+		l.add(file, []string{"id"}, models.SeverityError, "schema", "id is required")
+		return
+	}
+
+	ext := filepath.Ext(file.path)
+	if name := strings.TrimSuffix(filepath.Base(file.path), ext); file.single && name != id {
+		l.add(file, []string{"id"}, models.SeverityError, "filename",
+			fmt.Sprintf("id '%s' does not match the file name; ssdctl repair renames it to %s%s", id, id, ext))
+	}
+	if other, exists := seen[id]; exists {
+		l.add(file, []string{"id"}, models.SeverityError, "duplicate-id",
+			fmt.Sprintf("id '%s' is already defined in %s", id, other))
+		return
+	}
+	seen[id] = file.path
+}
+
+// checkRef reports a reference to a config or experiment that is not
+// defined in the linted directories.
+func (l *linter) checkRef(file lintFile, path []string, ref string) {
+	if id, ok := strings.CutPrefix(ref, ExperimentRefPrefix); ok {
+		if l.opts.ExperimentDir != "" && l.experiments[id] == "" {
+			l.add(file, path, models.SeverityError, "reference", fmt.Sprintf("experiment '%s' does not exist", id))
+		}
+		return
+	}
+	if l.opts.ConfigDir != "" && l.configs[ConfigIDFromRef(ref)] == "" {
+		l.add(file, path, models.SeverityError, "reference", fmt.Sprintf("config '%s' does not exist", ConfigIDFromRef(ref)))
+	}
+}
+
+func (l *linter) add(file lintFile, path []string, severity, rule, message string) {
+	// Field paths name the flat form; a resource keeps the ID in metadata and
+	// everything else in spec
+	if file.resource && len(path) > 0 {
+		if path[0] == "id" {
+			path = []string{"metadata", "id"}
+		} else {
+			path = append([]string{"spec"}, path...)
+		}
+	}
+	issue := models.LintIssue{File: file.path, Severity: severity, Rule: rule, Message: message}
+	if node := locateNode(file.root, path); node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	if severity == models.SeverityError {
+		l.report.Errors++
+	}
+	l.report.Issues = append(l.report.Issues, issue)
+}
+
+var errorLine = regexp.MustCompile(`line (\d+)`)
+
+func (l *linter) parseError(file lintFile, err error) {
+	issue := models.LintIssue{File: file.path, Severity: models.SeverityError, Rule: "parse", Message: err.Error()}
+	if match := errorLine.FindStringSubmatch(err.Error()); match != nil {
+		issue.Line, _ = strconv.Atoi(match[1])
+	}
+	l.report.Errors++
+	l.report.Issues = append(l.report.Issues, issue)
+}
+
+// locateNode follows a field path through a node tree and returns the
+// deepest node it reaches; for a mapping key that is the key node. An index
+// into a mapping's "ids" skips the segment when the mapping is written as a
+// bare list.
+func locateNode(root *yaml.Node, path []string) *yaml.Node {
+	if root == nil {
+		return nil
+	}
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	found := node
+	for _, segment := range path {
+		switch node.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					found, next = node.Content[i], node.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return found
+			}
+			node = next
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(segment)
+			if err != nil {
+				continue
+			}
+			if i >= len(node.Content) {
+				return found
+			}
+			node = node.Content[i]
+			found = node
+		default:
+			return found
+		}
+	}
+	return found
+}
+
+// schedulePath points at the first schedule field that is set.
+func schedulePath(schedule models.Schedule) []string {
+	switch {
+	case schedule.ActiveFrom != "":
+		return []string{"activeFrom"}
+	case schedule.ActiveUntil != "":
+		return []string{"activeUntil"}
+	}
+	return []string{"timezone"}
+}
+
+type specificPart struct {
+	path   []string
+	config models.SpecificConfig
+}
+
+// specificParts splits a specific config into one config per datasource key,
+// rule, fallback host and exclude key.
+func specificParts(config models.SpecificConfig) []specificPart {
+	var parts []specificPart
+	for _, section := range []struct {
+		name     string
+		mappings map[string]models.Mapping
+		wrap     func(map[string]models.Mapping) models.DataSource
+	}{
+		{"hosts", config.DataSource.Hosts, func(m map[string]models.Mapping) models.DataSource { return models.DataSource{Hosts: m} }},
+		{"urls", config.DataSource.URLs, func(m map[string]models.Mapping) models.DataSource { return models.DataSource{URLs: m} }},
+		{"pages", config.DataSource.Pages, func(m map[string]models.Mapping) models.DataSource { return models.DataSource{Pages: m} }},
+	} {
+		for _, key := range sortedKeys(section.mappings) {
+			parts = append(parts, specificPart{
+				path:   []string{"datasource", section.name, key},
+				config: models.SpecificConfig{DataSource: section.wrap(map[string]models.Mapping{key: section.mappings[key]})},
+			})
+		}
+	}
+
+	for i, rule := range config.Rules {
+		parts = append(parts, specificPart{
+			path:   []string{"rules", strconv.Itoa(i)},
+			config: models.SpecificConfig{Rules: []models.Rule{rule}},
+		})
+	}
+
+	if fallback := config.Fallback; fallback != nil {
+		for _, key := range sortedKeys(fallback.Hosts) {
+			parts = append(parts, specificPart{
+				path:   []string{"fallback", "hosts", key},
+				config: models.SpecificConfig{Fallback: &models.Fallback{Hosts: map[string]models.StringSlice{key: fallback.Hosts[key]}}},
+			})
+		}
+	}
+
+	if exclude := config.Exclude; exclude != nil {
+		for _, section := range []struct {
+			name  string
+			lists map[string]models.StringSlice
+			wrap  func(map[string]models.StringSlice) *models.Exclude
+		}{
+			{"hosts", exclude.Hosts, func(m map[string]models.StringSlice) *models.Exclude { return &models.Exclude{Hosts: m} }},
+			{"urls", exclude.URLs, func(m map[string]models.StringSlice) *models.Exclude { return &models.Exclude{URLs: m} }},
+			{"pages", exclude.Pages, func(m map[string]models.StringSlice) *models.Exclude { return &models.Exclude{Pages: m} }},
+		} {
+			for _, key := range sortedKeys(section.lists) {
+				parts = append(parts, specificPart{
+					path:   []string{"exclude", section.name, key},
+					config: models.SpecificConfig{Exclude: section.wrap(map[string]models.StringSlice{key: section.lists[key]})},
+				})
+			}
+		}
+	}
+	return parts
+}
+
+type specificRef struct {
+	path []string
+	id   string
+}
+
+// specificRefs lists every config ID a specific config refers to with the
+// path it is written at.
+func specificRefs(config models.SpecificConfig) []specificRef {
+	var refs []specificRef
+	addAll := func(ids []string, path ...string) {
+		for i, id := range ids {
+			refs = append(refs, specificRef{append(append([]string{}, path...), strconv.Itoa(i)), id})
+		}
+	}
+
+	for _, section := range []struct {
+		name     string
+		mappings map[string]models.Mapping
+	}{
+		{"hosts", config.DataSource.Hosts},
+		{"urls", config.DataSource.URLs},
+		{"pages", config.DataSource.Pages},
+	} {
+		for _, key := range sortedKeys(section.mappings) {
+			addAll(section.mappings[key].IDs, "datasource", section.name, key, "ids")
+		}
+	}
+	for i, rule := range config.Rules {
+		addAll(rule.IDs, "rules", strconv.Itoa(i), "ids")
+	}
+	if fallback := config.Fallback; fallback != nil {
+		addAll(fallback.IDs, "fallback", "ids")
+		for _, key := range sortedKeys(fallback.Hosts) {
+			addAll(fallback.Hosts[key], "fallback", "hosts", key)
+		}
+	}
+	if exclude := config.Exclude; exclude != nil {
+		for _, section := range []struct {
+			name  string
+			lists map[string]models.StringSlice
+		}{
+			{"hosts", exclude.Hosts},
+			{"urls", exclude.URLs},
+			{"pages", exclude.Pages},
+		} {
+			for _, key := range sortedKeys(section.lists) {
+				addAll(section.lists[key], "exclude", section.name, key)
+			}
+		}
+	}
+	return refs
+}
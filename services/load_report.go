@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadMode decides what happens when a file can not be loaded at startup.
+type LoadMode string
+
+const (
+	LoadStrict  LoadMode = "strict"  // the first bad file fails the startup
+	LoadLenient LoadMode = "lenient" // bad files are skipped and reported
+)
+
+// ParseLoadMode reads LOAD_MODE; it defaults to strict.
+func ParseLoadMode(value string) (LoadMode, error) {
+	switch LoadMode(value) {
+	case "", LoadStrict:
+		return LoadStrict, nil
+	case LoadLenient:
+		return LoadLenient, nil
+	}
+	return "", fmt.Errorf("unknown load mode '%s', expected strict or lenient", value)
+}
+
+// LoadOptions controls how the YAML directories are loaded at startup.
+type LoadOptions struct {
+	Mode         LoadMode
+	DeriveIDs    bool // a file without an ID takes its file name as ID
+	AllowMissing bool // a missing directory holds nothing
+}
+
+// loadRecorder collects the outcome of loading a directory.
+type loadRecorder struct {
+	LoadOptions
+	loadedAt time.Time
+	loaded   int
+	failed   []models.LoadDiagnostic
+	warnings []models.LoadDiagnostic
+	files    map[string]string // kind:id to the file it was loaded from
+}
+
+func newLoadRecorder(opts LoadOptions) loadRecorder {
+	if opts.Mode == "" {
+		opts.Mode = LoadStrict
+	}
+	return loadRecorder{LoadOptions: opts, loadedAt: time.Now().UTC(), files: map[string]string{}}
+}
+
+// checkID applies the ID rules to a loaded document. A missing ID is taken
+// from the file name when DeriveIDs is set and the file holds a single
+// document, and is an error otherwise. An ID that an earlier document
+// already defined is a duplicateIDError; see documentFiles for which file
+// wins. A single-document file whose name does not match the ID is only a
+// warning.
+func (r *loadRecorder) checkID(kind string, src source, single bool, id *string) error {
+	name := strings.TrimSuffix(filepath.Base(src.path), filepath.Ext(src.path))
+	if *id == "" {
+		if !r.DeriveIDs || !single {
+			return errors.New("id is missing")
+		}
+		*id = name
+	}
+
+	if other, exists := r.files[kind+":"+*id]; exists {
+		return duplicateIDError{id: *id, other: other}
+	}
+	r.files[kind+":"+*id] = src.String()
+
+	if single && name != *id {
+		r.warnings = append(r.warnings, models.LoadDiagnostic{Kind: kind, File: src.path,
+			Error: fmt.Sprintf("file name does not match id '%s'", *id)})
+	}
+	return nil
+}
+
+// duplicateIDError is returned by checkID for a document whose ID an
+// earlier document already defined.
+type duplicateIDError struct {
+	id, other string
+}
+
+func (e duplicateIDError) Error() string {
+	return fmt.Sprintf("id '%s' is already defined in %s", e.id, e.other)
+}
+
+// loadDocuments passes every document of the files in dir to load, in the
+// order of documentFiles. Files and documents that fail to load are
+// recorded as kind. A document whose ID is already loaded is skipped with a
+// warning in either mode, since the first definition is kept.
+func (r *loadRecorder) loadDocuments(kind, dir string, load func(src source, doc *yaml.Node, single bool) error) error {
+	files, err := documentFiles(dir)
+	if errors.Is(err, fs.ErrNotExist) && r.AllowMissing {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("directory %s could not be read: %w", dir, err)
+	}
+
+	for _, file := range files {
+		if file.err != nil {
+			var pathErr *fs.PathError
+			if errors.As(file.err, &pathErr) {
+				err = fmt.Errorf("file could not be read: %w", file.err)
+			} else {
+				err = fmt.Errorf("file could not be parsed: %w", file.err)
+			}
+			if err := r.fail(kind, file.path, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for index, doc := range file.docs {
+			if doc == nil {
+				continue
+			}
+			src := source{path: file.path, index: index}
+			err := load(src, doc, len(file.docs) == 1)
+			var duplicate duplicateIDError
+			if errors.As(err, &duplicate) {
+				r.warnings = append(r.warnings, models.LoadDiagnostic{Kind: kind, File: src.String(), Error: err.Error()})
+				continue
+			}
+			if err != nil {
+				if err := r.fail(kind, src.String(), err); err != nil {
+					return err
+				}
+				continue
+			}
+			r.loaded++
+		}
+	}
+	return nil
+}
+
+// fail records a file that could not be loaded. In strict mode it returns
+// the error, prefixed with the file, so loading stops; in lenient mode the
+// file is skipped.
+func (r *loadRecorder) fail(kind, path string, err error) error {
+	if r.Mode != LoadLenient {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	r.failed = append(r.failed, models.LoadDiagnostic{Kind: kind, File: path, Error: err.Error()})
+	return nil
+}
+
+func (r *loadRecorder) report() models.LoadReport {
+	return models.LoadReport{
+		Mode:     string(r.Mode),
+		LoadedAt: r.loadedAt,
+		Loaded:   r.loaded,
+		Failed:   append([]models.LoadDiagnostic{}, r.failed...),
+		Warnings: append([]models.LoadDiagnostic{}, r.warnings...),
+	}
+}
+
+// documentFile is a file of a directory with its parsed documents, or the
+// error reading it.
+type documentFile struct {
+	path     string
+	docs     []*yaml.Node
+	err      error
+	misnamed bool
+}
+
+// documentFiles reads the .yaml, .yml and .json files of a directory in
+// load order: single-document files named after the ID they contain come
+// first, so they win over misnamed copies, and otherwise files are ordered
+// by name.
+func documentFiles(dir string) ([]documentFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []documentFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isDocumentFile(entry.Name()) {
+			continue
+		}
+		file := documentFile{path: filepath.Join(dir, entry.Name())}
+		file.docs, file.err = readDocuments(file.path)
+
+		// Files that do not parse are reported when they are loaded
+		file.misnamed = true
+		if id, ok := singleDocumentID(file.docs, file.err); ok {
+			file.misnamed = id != "" && id != strings.TrimSuffix(entry.Name(), filepath.Ext(file.path))
+		}
+		files = append(files, file)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return !files[i].misnamed && files[j].misnamed
+	})
+	return files, nil
+}
+
+// singleDocumentID returns the ID of a file that holds exactly one document.
+func singleDocumentID(docs []*yaml.Node, err error) (string, bool) {
+	if err != nil || len(docs) != 1 || docs[0] == nil {
+		return "", false
+	}
+	var doc struct {
+		ID string `yaml:"id"`
+	}
+	if docs[0].Decode(&doc) != nil {
+		return "", false
+	}
+	return doc.ID, true
+}
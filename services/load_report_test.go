@@ -0,0 +1,48 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateIDsAreWarnings(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"A.yaml":    "id: A\nactions: []\n",
+		"copy.yaml": "id: A\nactions:\n  - type: remove\n    selector: .old\n",
+		"B.yaml":    "id: B\nactions: []\n---\nid: B\nactions: []\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, mode := range []LoadMode{LoadStrict, LoadLenient} {
+		t.Run(string(mode), func(t *testing.T) {
+			configs, err := NewConfigService(dir, LoadOptions{Mode: mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config, _ := configs.GetConfigByID("A"); len(config.Actions) != 0 {
+				t.Error("the misnamed copy won over A.yaml")
+			}
+			report := configs.LoadReport()
+			if report.Loaded != 2 || len(report.Failed) != 0 {
+				t.Errorf("loaded %d with %d failed, want 2 and none", report.Loaded, len(report.Failed))
+			}
+			var duplicates []string
+			for _, warning := range report.Warnings {
+				if strings.Contains(warning.Error, "already defined") {
+					duplicates = append(duplicates, warning.File)
+				}
+			}
+			want := []string{filepath.Join(dir, "B.yaml") + " (document 2)", filepath.Join(dir, "copy.yaml")}
+			if strings.Join(duplicates, ",") != strings.Join(want, ",") {
+				t.Errorf("duplicate warnings for %v, want %v", duplicates, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+)
+
+// indexedMapping is a compiled mapping tagged with the SpecificConfig it
+// belongs to.
+type indexedMapping struct {
+	source string
+	compiledMapping
+}
+
+// indexedRule is a compiled rule tagged with its SpecificConfig.
+type indexedRule struct {
+	source string
+	compiledRule
+}
+
+// matchIndex is an immutable lookup structure built from every compiled
+// SpecificConfig. It is rebuilt on each write and swapped atomically, so
+// readers never take the service mutex. Compound rules cannot be keyed and
+// are evaluated one by one.
+type matchIndex struct {
+	hosts *patternIndex
+	urls  *patternIndex
+	pages *patternIndex
+	rules []*indexedRule
+
+	excludeHosts *patternIndex
+	excludeURLs  *patternIndex
+	excludePages *patternIndex
+
+	// fallbackHosts and fallbackIDs are used when nothing else matches
+	fallbackHosts *patternIndex
+	fallbackIDs   []string
+
+	// normalize holds the options the keys were compiled with, so requests
+	// are normalized consistently with the index they are matched against.
+	normalize NormalizeOptions
+	filters   []ConfigFilter
+	assigner  VariantAssigner
+	clock     Clock // evaluates schedules when a request carries no time
+}
+
+// patternIndex indexes the keys of one datasource map. Exact keys live in a
+// hash map, wildcard host and url keys in a trie and everything else (regexes
+// and page globs) is scanned linearly.
+type patternIndex struct {
+	exact map[string][]*indexedMapping
+	trie  *trieNode
+	scan  []*indexedMapping
+
+	// sep splits values into trie segments; reversed walks hosts from the
+	// top-level domain down; restMin is the number of segments a trailing
+	// catch-all needs ("*." hosts need at least one, "/**" urls none).
+	sep      string
+	reversed bool
+	restMin  int
+}
+
+// trieNode is a node in a host suffix trie or a url path trie.
+type trieNode struct {
+	children map[string]*trieNode
+	any      *trieNode         // "*" or ":param" segment
+	terminal []*indexedMapping // patterns ending at this node
+	rest     []*indexedMapping // patterns ending in a catch-all at this node
+}
+
+func newHostIndex() *patternIndex {
+	return &patternIndex{exact: make(map[string][]*indexedMapping), trie: &trieNode{}, sep: ".", reversed: true, restMin: 1}
+}
+
+func newURLIndex() *patternIndex {
+	return &patternIndex{exact: make(map[string][]*indexedMapping), trie: &trieNode{}, sep: "/", restMin: 0}
+}
+
+func newPageIndex() *patternIndex {
+	return &patternIndex{exact: make(map[string][]*indexedMapping)}
+}
+
+// buildMatchIndex indexes all compiled configs. Sources are visited in ID
+// order so the index layout does not depend on map iteration.
+func buildMatchIndex(compiled map[string]*compiledSpecificConfig, opts NormalizeOptions, filters []ConfigFilter) *matchIndex {
+	index := &matchIndex{
+		hosts:         newHostIndex(),
+		urls:          newURLIndex(),
+		pages:         newPageIndex(),
+		excludeHosts:  newHostIndex(),
+		excludeURLs:   newURLIndex(),
+		excludePages:  newPageIndex(),
+		fallbackHosts: newHostIndex(),
+		normalize:     opts,
+		filters:       filters,
+	}
+
+	ids := make([]string, 0, len(compiled))
+	for id := range compiled {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		config := compiled[id]
+		index.hosts.add(id, config.hosts)
+		index.urls.add(id, config.urls)
+		index.pages.add(id, config.pages)
+		for _, rule := range config.rules {
+			index.rules = append(index.rules, &indexedRule{source: id, compiledRule: rule})
+		}
+		index.excludeHosts.add(id, config.excludeHosts)
+		index.excludeURLs.add(id, config.excludeURLs)
+		index.excludePages.add(id, config.excludePages)
+		index.fallbackHosts.add(id, config.fallbackHosts)
+		index.fallbackIDs = appendUnique(index.fallbackIDs, config.fallbackIDs...)
+	}
+
+	return index
+}
+
+func (idx *patternIndex) add(source string, mappings []compiledMapping) {
+	for _, mapping := range mappings {
+		entry := &indexedMapping{source: source, compiledMapping: mapping}
+
+		switch {
+		case mapping.pattern.kind == patternExact:
+			idx.exact[mapping.pattern.raw] = append(idx.exact[mapping.pattern.raw], entry)
+		case mapping.pattern.kind == patternRegex || idx.trie == nil:
+			idx.scan = append(idx.scan, entry)
+		default:
+			idx.insert(entry)
+		}
+	}
+}
+
+func (idx *patternIndex) insert(entry *indexedMapping) {
+	segments := entry.pattern.segments
+	if idx.reversed {
+		segments = reverseSegments(segments)
+	}
+
+	node := idx.trie
+	for i, segment := range segments {
+		if idx.isCatchAll(segment, i == len(segments)-1) {
+			node.rest = append(node.rest, entry)
+			return
+		}
+
+		if segment == "*" || strings.HasPrefix(segment, ":") {
+			if node.any == nil {
+				node.any = &trieNode{}
+			}
+			node = node.any
+			continue
+		}
+
+		if node.children == nil {
+			node.children = make(map[string]*trieNode)
+		}
+		child, exists := node.children[segment]
+		if !exists {
+			child = &trieNode{}
+			node.children[segment] = child
+		}
+		node = child
+	}
+	node.terminal = append(node.terminal, entry)
+}
+
+// isCatchAll reports whether the final segment swallows every remaining one:
+// a leading "*" label for hosts and a trailing "**" for urls.
+func (idx *patternIndex) isCatchAll(segment string, last bool) bool {
+	if !last {
+		return false
+	}
+	if idx.reversed {
+		return segment == "*"
+	}
+	return segment == "**"
+}
+
+// lookup returns every mapping whose key matches value.
+func (idx *patternIndex) lookup(value string) []*indexedMapping {
+	matches := append([]*indexedMapping(nil), idx.exact[value]...)
+
+	if idx.trie != nil {
+		var segments []string
+		if idx.reversed {
+			segments = reverseSegments(strings.Split(value, idx.sep))
+		} else {
+			segments = splitPath(value)
+		}
+		matches = idx.trie.collect(segments, idx.restMin, matches)
+	}
+
+	for _, entry := range idx.scan {
+		if ok, _ := entry.pattern.match(value); ok {
+			matches = append(matches, entry)
+		}
+	}
+
+	return matches
+}
+
+func (n *trieNode) collect(segments []string, restMin int, matches []*indexedMapping) []*indexedMapping {
+	if len(segments) >= restMin {
+		matches = append(matches, n.rest...)
+	}
+	if len(segments) == 0 {
+		return append(matches, n.terminal...)
+	}
+
+	if child, exists := n.children[segments[0]]; exists {
+		matches = child.collect(segments[1:], restMin, matches)
+	}
+	if n.any != nil {
+		matches = n.any.collect(segments[1:], restMin, matches)
+	}
+	return matches
+}
+
+// matchRules returns the mappings of every rule whose condition holds.
+func (idx *matchIndex) matchRules(req MatchRequest) []*indexedMapping {
+	var matches []*indexedMapping
+	for _, rule := range idx.rules {
+		if rule.condition.matches(req) {
+			matches = append(matches, &indexedMapping{source: rule.source, compiledMapping: rule.compiledMapping})
+		}
+	}
+	return matches
+}
+
+// exclusions returns every veto that applies to the request, keyed by the
+// vetoed config ID.
+func (idx *matchIndex) exclusions(req MatchRequest) map[string][]models.MatchExclusion {
+	excluded := make(map[string][]models.MatchExclusion)
+	if req.Host != "" {
+		vetoes("host", idx.excludeHosts.lookup(req.Host), excluded)
+	}
+	if req.URL != "" {
+		vetoes("url", idx.excludeURLs.lookup(req.URL), excluded)
+	}
+	if req.Page != "" {
+		vetoes("page", idx.excludePages.lookup(req.Page), excluded)
+	}
+	return excluded
+}
+
+// filter returns the first reason a filter rejects id for, or "" when every
+// filter allows it.
+func (idx *matchIndex) filter(id string, req MatchRequest) string {
+	for _, filter := range idx.filters {
+		if ok, reason := filter.Allow(id, req); !ok {
+			return reason
+		}
+	}
+	return ""
+}
+
+// fallback returns the fallback IDs for a request and a reason describing
+// where they came from. Host fallbacks win over global ones, the most
+// specific host pattern first.
+func (idx *matchIndex) fallback(host string) ([]string, string) {
+	if host != "" {
+		matches := idx.fallbackHosts.lookup(host)
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].pattern.broadness < matches[j].pattern.broadness
+		})
+
+		var ids []string
+		for _, mapping := range matches {
+			ids = appendUnique(ids, mapping.ids...)
+		}
+		if len(ids) > 0 {
+			return ids, fmt.Sprintf("no mapping matched; using the fallback for host pattern '%s'", matches[0].pattern.raw)
+		}
+	}
+
+	if len(idx.fallbackIDs) > 0 {
+		return idx.fallbackIDs, "no mapping matched; using the global fallback"
+	}
+	return nil, "no mapping matched and no fallback is configured"
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if !containsString(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+func reverseSegments(segments []string) []string {
+	reversed := make([]string, len(segments))
+	for i, segment := range segments {
+		reversed[len(segments)-1-i] = segment
+	}
+	return reversed
+}
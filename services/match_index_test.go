@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+	"testing"
+)
+
+// linearLookup is the scan the index replaced: every mapping is compared
+// against the value.
+func linearLookup(mappings []compiledMapping, value string) []string {
+	var matched []string
+	for _, mapping := range mappings {
+		if ok, _ := mapping.pattern.match(value); ok {
+			matched = append(matched, mapping.pattern.raw)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+func indexLookup(idx *patternIndex, value string) []string {
+	var matched []string
+	for _, mapping := range idx.lookup(value) {
+		matched = append(matched, mapping.pattern.raw)
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+func randomHostKey(r *rand.Rand, labels []string) string {
+	n := 1 + r.Intn(4)
+	parts := make([]string, n)
+	for i := range parts {
+		switch x := r.Intn(10); {
+		case x == 0 && i == 0:
+			parts[i] = "*"
+		case x == 1:
+			parts[i] = "*"
+		default:
+			parts[i] = labels[r.Intn(len(labels))]
+		}
+	}
+	if r.Intn(20) == 0 {
+		return "re:" + labels[r.Intn(len(labels))] + `\..*`
+	}
+	return strings.Join(parts, ".")
+}
+
+func randomURLKey(r *rand.Rand, segments []string) string {
+	n := r.Intn(4)
+	parts := make([]string, n)
+	for i := range parts {
+		switch x := r.Intn(10); {
+		case x == 0:
+			parts[i] = "*"
+		case x == 1:
+			parts[i] = ":id"
+		default:
+			parts[i] = segments[r.Intn(len(segments))]
+		}
+	}
+	if r.Intn(5) == 0 {
+		parts = append(parts, "**")
+	}
+	if r.Intn(20) == 0 {
+		return "re:/" + segments[r.Intn(len(segments))] + "/[0-9]+"
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+func randomValue(r *rand.Rand, words []string, sep string, max int) string {
+	n := r.Intn(max + 1)
+	parts := make([]string, n)
+	for i := range parts {
+		if r.Intn(8) == 0 {
+			parts[i] = fmt.Sprint(r.Intn(100))
+		} else {
+			parts[i] = words[r.Intn(len(words))]
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+func compileKeys(t *testing.T, keys []string, compile func(string) (*pattern, error)) []compiledMapping {
+	t.Helper()
+	mappings := make(map[string]models.Mapping, len(keys))
+	for _, key := range keys {
+		mappings[key] = models.Mapping{IDs: models.StringSlice{key}}
+	}
+	compiled, err := compileMappings(mappings, compile, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compiled
+}
+
+func TestPatternIndexMatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	labels := []string{"example", "com", "www", "shop", "de", "api", "a"}
+	segments := []string{"products", "orders", "cart", "docs", "1", "api"}
+
+	tests := []struct {
+		name    string
+		keys    int
+		index   func() *patternIndex
+		key     func() string
+		compile func(string) (*pattern, error)
+		value   func() string
+	}{
+		{
+			name:    "hosts",
+			keys:    300,
+			index:   newHostIndex,
+			key:     func() string { return randomHostKey(r, labels) },
+			compile: compileHostPattern,
+			value:   func() string { return randomValue(r, labels, ".", 5) },
+		},
+		{
+			name:    "urls",
+			keys:    300,
+			index:   newURLIndex,
+			key:     func() string { return randomURLKey(r, segments) },
+			compile: compileURLPattern,
+			value:   func() string { return "/" + randomValue(r, segments, "/", 5) },
+		},
+		{
+			name:  "pages",
+			keys:  30,
+			index: newPageIndex,
+			key: func() string {
+				page := randomValue(r, segments, "-", 2)
+				if r.Intn(3) == 0 {
+					return page + "*"
+				}
+				return page
+			},
+			compile: compilePagePattern,
+			value:   func() string { return randomValue(r, segments, "-", 2) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			var keys []string
+			for len(keys) < tt.keys {
+				if key := tt.key(); !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+			mappings := compileKeys(t, keys, tt.compile)
+			idx := tt.index()
+			idx.add("test", mappings)
+
+			for i := 0; i < 5000; i++ {
+				value := tt.value()
+				want := linearLookup(mappings, value)
+				got := indexLookup(idx, value)
+				if strings.Join(got, " ") != strings.Join(want, " ") {
+					t.Fatalf("lookup(%q) = %v, linear scan = %v", value, got, want)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkService builds a service with n host and n url mappings, a
+// quarter of them wildcards.
+func benchmarkService(b *testing.B, n int) *SpecificConfigService {
+	b.Helper()
+	config := models.SpecificConfig{
+		ID: "bench",
+		DataSource: models.DataSource{
+			Hosts: make(map[string]models.Mapping, n),
+			URLs:  make(map[string]models.Mapping, n),
+		},
+	}
+	for i := 0; i < n; i++ {
+		host := fmt.Sprintf("shop%d.example.com", i)
+		url := fmt.Sprintf("/products/%d/details", i)
+		if i%4 == 0 {
+			host = fmt.Sprintf("*.brand%d.example.com", i)
+			url = fmt.Sprintf("/category/%d/**", i)
+		}
+		config.DataSource.Hosts[host] = models.Mapping{IDs: models.StringSlice{fmt.Sprintf("H%d", i)}}
+		config.DataSource.URLs[url] = models.Mapping{IDs: models.StringSlice{fmt.Sprintf("U%d", i)}}
+	}
+
+	return newTestSpecificService(b, NormalizeOptions{}, config)
+}
+
+func BenchmarkLookup(b *testing.B) {
+	for _, n := range []int{10_000, 100_000} {
+		s := benchmarkService(b, n)
+		requests := []MatchRequest{
+			{Host: fmt.Sprintf("shop%d.example.com", n/2+1), URL: fmt.Sprintf("/products/%d/details", n/3+1)},
+			{Host: fmt.Sprintf("www.brand%d.example.com", n/2), URL: fmt.Sprintf("/category/%d/a/b", n/4*4)},
+			{Host: "unknown.example.org", URL: "/nothing/here"},
+		}
+
+		b.Run(fmt.Sprintf("mappings=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.GetMatchingConfigs(requests[i%len(requests)])
+			}
+		})
+		b.Run(fmt.Sprintf("mappings=%d/parallel", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					s.GetMatchingConfigs(requests[i%len(requests)])
+					i++
+				}
+			})
+		})
+	}
+}
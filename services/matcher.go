@@ -0,0 +1,444 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"ssd-assignment-api/models"
+	"strings"
+	"time"
+)
+
+// Default points added by a host, url and page match when a SpecificConfig
+// does not override them.
+const (
+	defaultHostWeight = 3
+	defaultURLWeight  = 2
+	defaultPageWeight = 1
+	defaultRuleWeight = 4
+)
+
+// MatchRequest carries everything a request can be matched on. The request
+// context fields describe the visitor and are forwarded by the runtime.
+type MatchRequest struct {
+	Host  string
+	URL   string
+	Page  string
+	Query url.Values
+
+	UserAgent string
+	Device    string   // one of the Device* classes
+	Languages []string // lowercased, most preferred first
+	Headers   http.Header
+	Cookies   map[string]string
+
+	// At is the instant schedules are evaluated at; the service clock is
+	// used when it is zero.
+	At time.Time
+
+	// VisitorID identifies the visitor for sticky experiment bucketing.
+	VisitorID string
+}
+
+// ConfigFilter decides whether a matched config ID may be served for a
+// request. A filter that rejects an ID returns the reason shown in
+// explanations. Allow runs on every lookup and must not block, so filters
+// read a published snapshot of their state rather than taking a lock.
+type ConfigFilter interface {
+	Allow(id string, req MatchRequest) (bool, string)
+}
+
+// VariantAssigner replaces a matched experiment reference with the config IDs
+// of the variant the visitor is assigned to. It returns false for references
+// that are not experiments.
+type VariantAssigner interface {
+	Assign(ref string, req MatchRequest) (models.ExperimentAssignment, bool)
+}
+
+// Language returns the visitor's most preferred language, if any.
+func (r MatchRequest) Language() string {
+	if len(r.Languages) == 0 {
+		return ""
+	}
+	return r.Languages[0]
+}
+
+// IsEmpty reports whether the request has nothing to match on.
+func (r MatchRequest) IsEmpty() bool {
+	return r.Host == "" && r.URL == "" && r.Page == "" && len(r.Query) == 0
+}
+
+// compiledMapping is a datasource entry with its key compiled to a pattern.
+type compiledMapping struct {
+	pattern  *pattern
+	ids      []string
+	weight   int
+	priority int
+	stop     bool
+	query    []queryMatcher
+	window   *window // nil when the mapping is always active
+}
+
+// compiledSpecificConfig holds the compiled datasource of a SpecificConfig.
+type compiledSpecificConfig struct {
+	hosts []compiledMapping
+	urls  []compiledMapping
+	pages []compiledMapping
+	rules []compiledRule
+
+	fallbackIDs   []string
+	fallbackHosts []compiledMapping
+
+	excludeHosts []compiledMapping
+	excludeURLs  []compiledMapping
+	excludePages []compiledMapping
+}
+
+// matchCandidate accumulates the score of a single config ID.
+type matchCandidate struct {
+	id            string
+	priority      int
+	score         int
+	broadness     int
+	contributions []models.MatchContribution
+}
+
+// compileSpecificConfig compiles the datasource and rules of a config. Host
+// and url keys are normalized the same way as incoming requests.
+func compileSpecificConfig(config models.SpecificConfig, opts NormalizeOptions) (*compiledSpecificConfig, error) {
+	hostWeight, urlWeight, pageWeight := defaultHostWeight, defaultURLWeight, defaultPageWeight
+	ruleWeight := defaultRuleWeight
+	if w := config.Weights; w != nil {
+		hostWeight = weightOrDefault(w.Host, hostWeight)
+		urlWeight = weightOrDefault(w.URL, urlWeight)
+		pageWeight = weightOrDefault(w.Page, pageWeight)
+		ruleWeight = weightOrDefault(w.Rule, ruleWeight)
+	}
+
+	compiler := patternCompiler{opts}
+	hosts, err := compileMappings(config.DataSource.Hosts, compiler.host, hostWeight)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := compileMappings(config.DataSource.URLs, compiler.url, urlWeight)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := compileMappings(config.DataSource.Pages, compilePagePattern, pageWeight)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := compiler.rules(config.Rules, ruleWeight)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &compiledSpecificConfig{hosts: hosts, urls: urls, pages: pages, rules: rules}
+	if config.Fallback != nil {
+		compiled.fallbackIDs = config.Fallback.IDs
+		if compiled.fallbackHosts, err = compileMappings(toMappings(config.Fallback.Hosts), compiler.host, 0); err != nil {
+			return nil, fmt.Errorf("fallback: %w", err)
+		}
+	}
+
+	if exclude := config.Exclude; exclude != nil {
+		if compiled.excludeHosts, err = compileMappings(toMappings(exclude.Hosts), compiler.host, 0); err != nil {
+			return nil, fmt.Errorf("exclude: %w", err)
+		}
+		if compiled.excludeURLs, err = compileMappings(toMappings(exclude.URLs), compiler.url, 0); err != nil {
+			return nil, fmt.Errorf("exclude: %w", err)
+		}
+		if compiled.excludePages, err = compileMappings(toMappings(exclude.Pages), compilePagePattern, 0); err != nil {
+			return nil, fmt.Errorf("exclude: %w", err)
+		}
+	}
+
+	return compiled, nil
+}
+
+// toMappings wraps plain ID lists so they can be compiled like mappings.
+func toMappings(lists map[string]models.StringSlice) map[string]models.Mapping {
+	mappings := make(map[string]models.Mapping, len(lists))
+	for key, ids := range lists {
+		mappings[key] = models.Mapping{IDs: ids}
+	}
+	return mappings
+}
+
+func weightOrDefault(weight *int, fallback int) int {
+	if weight == nil {
+		return fallback
+	}
+	return *weight
+}
+
+// patternCompiler compiles keys after normalizing them with its options.
+type patternCompiler struct {
+	opts NormalizeOptions
+}
+
+func (pc patternCompiler) host(key string) (*pattern, error) {
+	return compileHostPattern(normalizeHostKey(key, pc.opts))
+}
+
+func (pc patternCompiler) url(key string) (*pattern, error) {
+	return compileURLPattern(normalizePathKey(key))
+}
+
+func compileMappings(mappings map[string]models.Mapping, compile func(string) (*pattern, error), weight int) ([]compiledMapping, error) {
+	keys := make([]string, 0, len(mappings))
+	for key := range mappings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	compiled := make([]compiledMapping, 0, len(keys))
+	for _, key := range keys {
+		p, err := compile(key)
+		if err != nil {
+			return nil, err
+		}
+		mapping := mappings[key]
+		query, err := compileQueryMatchers(mapping.Query)
+		if err != nil {
+			return nil, fmt.Errorf("mapping '%s': %w", key, err)
+		}
+		w, err := mappingWindow(mapping.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("mapping '%s': %w", key, err)
+		}
+		compiled = append(compiled, compiledMapping{
+			pattern:  p,
+			ids:      mapping.IDs,
+			weight:   weight,
+			priority: mapping.Priority,
+			stop:     mapping.Stop,
+			query:    query,
+			window:   w,
+		})
+	}
+	return compiled, nil
+}
+
+// scoreMappings adds the mapping weight to every config ID listed under a
+// matched mapping. Within one SpecificConfig an ID is counted once per rule
+// even when several keys match; the highest priority and then the most
+// specific key wins.
+func scoreMappings(candidates map[string]*matchCandidate, matches []*indexedMapping, rule, value string, query url.Values) {
+	type sourceID struct {
+		source string
+		id     string
+	}
+
+	best := make(map[sourceID]*indexedMapping)
+	var order []sourceID
+	for _, mapping := range matches {
+		for _, id := range mapping.ids {
+			key := sourceID{mapping.source, id}
+			current, seen := best[key]
+			if !seen {
+				order = append(order, key)
+			}
+			if !seen || outranks(mapping, current) {
+				best[key] = mapping
+			}
+		}
+	}
+
+	for _, key := range order {
+		mapping := best[key]
+		candidate, exists := candidates[key.id]
+		if !exists {
+			candidate = &matchCandidate{id: key.id}
+			candidates[key.id] = candidate
+		}
+
+		_, params := mapping.pattern.match(value)
+		matchedQuery, _ := matchQuery(mapping.query, query)
+		if len(candidate.contributions) == 0 || mapping.priority > candidate.priority {
+			candidate.priority = mapping.priority
+		}
+		candidate.score += mapping.weight
+		candidate.broadness += mapping.pattern.broadness
+		candidate.contributions = append(candidate.contributions, models.MatchContribution{
+			SpecificConfig: key.source,
+			Rule:           rule,
+			Pattern:        mapping.pattern.raw,
+			Points:         mapping.weight,
+			Priority:       mapping.priority,
+			Broadness:      mapping.pattern.broadness,
+			Params:         params,
+			Query:          matchedQuery,
+		})
+	}
+}
+
+func mappingWindow(schedule models.Schedule) (*window, error) {
+	if schedule.IsZero() {
+		return nil, nil
+	}
+	w, err := parseSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// filterActive drops mappings outside their schedule.
+func filterActive(matches []*indexedMapping, at time.Time) []*indexedMapping {
+	kept := matches[:0:0]
+	for _, mapping := range matches {
+		if mapping.window == nil || mapping.window.active(at) {
+			kept = append(kept, mapping)
+		}
+	}
+	return kept
+}
+
+// filterQuery drops mappings whose query constraints the request does not
+// satisfy.
+func filterQuery(matches []*indexedMapping, query url.Values) []*indexedMapping {
+	kept := matches[:0:0]
+	for _, mapping := range matches {
+		if _, ok := matchQuery(mapping.query, query); ok {
+			kept = append(kept, mapping)
+		}
+	}
+	return kept
+}
+
+func outranks(a, b *indexedMapping) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.pattern.broadness < b.pattern.broadness
+}
+
+// stopPriority returns the highest priority among matched mappings that
+// stop processing, and whether any of them did.
+func stopPriority(matches ...[]*indexedMapping) (int, bool) {
+	threshold, stopped := 0, false
+	for _, group := range matches {
+		for _, mapping := range group {
+			if mapping.stop && (!stopped || mapping.priority > threshold) {
+				threshold, stopped = mapping.priority, true
+			}
+		}
+	}
+	return threshold, stopped
+}
+
+// dropBelow removes mappings with a priority lower than threshold.
+func dropBelow(matches []*indexedMapping, threshold int) (kept, dropped []*indexedMapping) {
+	for _, mapping := range matches {
+		if mapping.priority < threshold {
+			dropped = append(dropped, mapping)
+			continue
+		}
+		kept = append(kept, mapping)
+	}
+	return kept, dropped
+}
+
+// describeMappings lists mappings that were matched but did not score.
+func describeMappings(mappings []*indexedMapping, rule string) []models.MatchContribution {
+	described := make([]models.MatchContribution, len(mappings))
+	for i, mapping := range mappings {
+		described[i] = models.MatchContribution{
+			SpecificConfig: mapping.source,
+			Rule:           rule,
+			Pattern:        mapping.pattern.raw,
+			Priority:       mapping.priority,
+			Broadness:      mapping.pattern.broadness,
+			ConfigIDs:      mapping.ids,
+		}
+	}
+	return described
+}
+
+// vetoes collects the exclusions that apply to a request, keyed by config ID.
+func vetoes(rule string, matches []*indexedMapping, into map[string][]models.MatchExclusion) {
+	for _, mapping := range matches {
+		for _, id := range mapping.ids {
+			into[id] = append(into[id], models.MatchExclusion{
+				ConfigID:       id,
+				SpecificConfig: mapping.source,
+				Rule:           rule,
+				Pattern:        mapping.pattern.raw,
+			})
+		}
+	}
+}
+
+// flattenExclusions lists vetoes in config ID order.
+func flattenExclusions(excluded map[string][]models.MatchExclusion) []models.MatchExclusion {
+	ids := make([]string, 0, len(excluded))
+	for id := range excluded {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var flat []models.MatchExclusion
+	for _, id := range ids {
+		flat = append(flat, excluded[id]...)
+	}
+	return flat
+}
+
+// rankCandidates orders candidates by priority, score, specificity and ID.
+func rankCandidates(candidates map[string]*matchCandidate) []models.CandidateExplanation {
+	sorted := make([]*matchCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		sorted = append(sorted, candidate)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return compareCandidates(sorted[i], sorted[j]) < 0
+	})
+
+	ranked := make([]models.CandidateExplanation, len(sorted))
+	for i, candidate := range sorted {
+		ranked[i] = models.CandidateExplanation{
+			ConfigID:      candidate.id,
+			Rank:          i + 1,
+			Priority:      candidate.priority,
+			Score:         candidate.score,
+			Broadness:     candidate.broadness,
+			Contributions: candidate.contributions,
+		}
+		if i > 0 {
+			ranked[i].RankedBy = rankedBy(sorted[i-1], candidate)
+		}
+	}
+	return ranked
+}
+
+// compareCandidates returns a negative number when a ranks before b.
+func compareCandidates(a, b *matchCandidate) int {
+	if a.priority != b.priority {
+		return b.priority - a.priority
+	}
+	if a.score != b.score {
+		return b.score - a.score
+	}
+	if a.broadness != b.broadness {
+		return a.broadness - b.broadness
+	}
+	return strings.Compare(a.id, b.id)
+}
+
+// rankedBy names the criterion that placed candidate after previous.
+func rankedBy(previous, candidate *matchCandidate) string {
+	switch {
+	case previous.priority != candidate.priority:
+		return "priority"
+	case previous.score != candidate.score:
+		return "score"
+	case previous.broadness != candidate.broadness:
+		return "specificity"
+	default:
+		return "id"
+	}
+}
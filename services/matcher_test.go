@@ -0,0 +1,309 @@
+package services
+
+import (
+	"os"
+	"reflect"
+	"ssd-assignment-api/models"
+	"testing"
+	"time"
+)
+
+// newTestSpecificService builds a service from configs without a YAML
+// directory.
+func newTestSpecificService(tb testing.TB, opts NormalizeOptions, configs ...models.SpecificConfig) *SpecificConfigService {
+	tb.Helper()
+	s := &SpecificConfigService{
+		configs:   make(map[string]models.SpecificConfig),
+		compiled:  make(map[string]*compiledSpecificConfig),
+		normalize: opts,
+		clock:     time.Now,
+	}
+	for _, config := range configs {
+		compiled, err := compileSpecificConfig(config, opts)
+		if err != nil {
+			tb.Fatalf("compile %s: %v", config.ID, err)
+		}
+		s.configs[config.ID] = config
+		s.compiled[config.ID] = compiled
+	}
+	s.rebuildIndex()
+	return s
+}
+
+func ids(ids ...string) models.Mapping {
+	return models.Mapping{IDs: ids}
+}
+
+func prio(priority int, stop bool, ids ...string) models.Mapping {
+	return models.Mapping{IDs: ids, Priority: priority, Stop: stop}
+}
+
+func weight(n int) *int {
+	return &n
+}
+
+func TestMatchPrecedence(t *testing.T) {
+	req := MatchRequest{Host: "www.example.com", URL: "/products/42", Page: "product"}
+
+	tests := []struct {
+		name    string
+		configs []models.SpecificConfig
+		want    []string
+		scores  map[string]int
+	}{
+		{
+			name: "default weights rank host over url over page",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("H")},
+				URLs:  map[string]models.Mapping{"/products/42": ids("U")},
+				Pages: map[string]models.Mapping{"product": ids("P")},
+			}}},
+			want:   []string{"H", "U", "P"},
+			scores: map[string]int{"H": 3, "U": 2, "P": 1},
+		},
+		{
+			name: "scores add up across rules",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("H")},
+				URLs:  map[string]models.Mapping{"/products/42": ids("UP")},
+				Pages: map[string]models.Mapping{"product": ids("UP")},
+			}}},
+			want:   []string{"H", "UP"},
+			scores: map[string]int{"H": 3, "UP": 3},
+		},
+		{
+			name: "rule weight beats host",
+			configs: []models.SpecificConfig{{ID: "s",
+				DataSource: models.DataSource{Hosts: map[string]models.Mapping{"www.example.com": ids("H")}},
+				Rules:      []models.Rule{{When: models.Condition{Page: "product"}, IDs: models.StringSlice{"R"}}},
+			}},
+			want:   []string{"R", "H"},
+			scores: map[string]int{"R": 4, "H": 3},
+		},
+		{
+			name: "custom weights override the defaults",
+			configs: []models.SpecificConfig{{ID: "s",
+				Weights: &models.Weights{Host: weight(1), Page: weight(5)},
+				DataSource: models.DataSource{
+					Hosts: map[string]models.Mapping{"www.example.com": ids("H")},
+					URLs:  map[string]models.Mapping{"/products/42": ids("U")},
+					Pages: map[string]models.Mapping{"product": ids("P")},
+				},
+			}},
+			want:   []string{"P", "U", "H"},
+			scores: map[string]int{"P": 5, "U": 2, "H": 1},
+		},
+		{
+			name: "weights apply per specific config",
+			configs: []models.SpecificConfig{
+				{ID: "a", Weights: &models.Weights{Host: weight(1)},
+					DataSource: models.DataSource{Hosts: map[string]models.Mapping{"www.example.com": ids("A")}}},
+				{ID: "b", DataSource: models.DataSource{Pages: map[string]models.Mapping{"product": ids("B")}}},
+			},
+			want:   []string{"A", "B"},
+			scores: map[string]int{"A": 1, "B": 1},
+		},
+		{
+			name: "an ID scores once per rule within a specific config",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("A"), "*.example.com": ids("A")},
+				URLs:  map[string]models.Mapping{"/products/42": ids("B")},
+				Pages: map[string]models.Mapping{"product": ids("B")},
+			}}},
+			want:   []string{"A", "B"},
+			scores: map[string]int{"A": 3, "B": 3},
+		},
+		{
+			name: "an ID scores in every specific config listing it",
+			configs: []models.SpecificConfig{
+				{ID: "a", DataSource: models.DataSource{Hosts: map[string]models.Mapping{"www.example.com": ids("A", "B")}}},
+				{ID: "b", DataSource: models.DataSource{Hosts: map[string]models.Mapping{"*.example.com": ids("B")}}},
+			},
+			want:   []string{"B", "A"},
+			scores: map[string]int{"B": 6, "A": 3},
+		},
+		{
+			name: "priority beats score",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("H")},
+				Pages: map[string]models.Mapping{"product": prio(1, false, "P")},
+			}}},
+			want: []string{"P", "H"},
+		},
+		{
+			name: "negative priority ranks below unprioritized",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": prio(-1, false, "H")},
+				Pages: map[string]models.Mapping{"product": ids("P")},
+			}}},
+			want: []string{"P", "H"},
+		},
+		{
+			name: "a candidate takes its highest priority",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("A")},
+				URLs:  map[string]models.Mapping{"/products/42": prio(2, false, "B")},
+				Pages: map[string]models.Mapping{"product": prio(3, false, "A")},
+			}}},
+			want: []string{"A", "B"},
+		},
+		{
+			name: "the higher priority key wins within a rule",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("A"), "*.example.com": prio(2, false, "A")},
+				URLs:  map[string]models.Mapping{"/products/42": prio(1, false, "B")},
+			}}},
+			want: []string{"A", "B"},
+		},
+		{
+			name: "stop drops lower priorities",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("H")},
+				URLs:  map[string]models.Mapping{"/products/42": prio(5, true, "U")},
+				Pages: map[string]models.Mapping{"product": prio(4, false, "P")},
+			}}},
+			want: []string{"U"},
+		},
+		{
+			name: "stop keeps equal and higher priorities",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": prio(5, false, "H")},
+				URLs:  map[string]models.Mapping{"/products/42": prio(5, true, "U")},
+				Pages: map[string]models.Mapping{"product": prio(6, false, "P")},
+			}}},
+			want: []string{"P", "H", "U"},
+		},
+		{
+			name: "the highest stopping priority sets the threshold",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": prio(1, true, "H")},
+				URLs:  map[string]models.Mapping{"/products/42": prio(3, true, "U")},
+				Pages: map[string]models.Mapping{"product": prio(2, false, "P")},
+			}}},
+			want: []string{"U"},
+		},
+		{
+			name: "stop applies across specific configs",
+			configs: []models.SpecificConfig{
+				{ID: "a", DataSource: models.DataSource{Hosts: map[string]models.Mapping{"www.example.com": ids("A")}}},
+				{ID: "b", DataSource: models.DataSource{Pages: map[string]models.Mapping{"product": prio(0, true, "B")}}},
+				{ID: "c", DataSource: models.DataSource{URLs: map[string]models.Mapping{"/products/42": prio(-1, false, "C")}}},
+			},
+			want: []string{"A", "B"},
+		},
+		{
+			name: "stop on a rule drops mappings",
+			configs: []models.SpecificConfig{{ID: "s",
+				DataSource: models.DataSource{Hosts: map[string]models.Mapping{"www.example.com": ids("H")}},
+				Rules:      []models.Rule{{When: models.Condition{URL: "/products/*"}, IDs: models.StringSlice{"R"}, Priority: 1, Stop: true}},
+			}},
+			want: []string{"R"},
+		},
+		{
+			name: "equal scores rank the more specific key first",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"*.example.com": ids("A"), "www.example.com": ids("B")},
+			}}},
+			want: []string{"B", "A"},
+		},
+		{
+			name: "parameters rank before globs and narrow globs before wide ones",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				URLs: map[string]models.Mapping{"/**": ids("A"), "/products/*": ids("B"), "/products/:id": ids("C")},
+			}}},
+			want: []string{"C", "B", "A"},
+		},
+		{
+			name: "broadness adds up across rules",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"*.example.com": ids("A"), "www.example.com": ids("B")},
+				Pages: map[string]models.Mapping{"product": ids("A"), "prod*": ids("B")},
+			}}},
+			want: []string{"A", "B"},
+		},
+		{
+			name: "score beats broadness",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"*.example.com": ids("A")},
+				URLs:  map[string]models.Mapping{"/products/42": ids("B")},
+			}}},
+			want: []string{"A", "B"},
+		},
+		{
+			name: "full ties rank by ID",
+			configs: []models.SpecificConfig{{ID: "s", DataSource: models.DataSource{
+				Hosts: map[string]models.Mapping{"www.example.com": ids("C", "A", "B")},
+			}}},
+			want: []string{"A", "B", "C"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSpecificService(t, NormalizeOptions{}, tt.configs...)
+			explanation := s.ExplainMatch(req)
+			if !reflect.DeepEqual(explanation.ConfigIDs, tt.want) {
+				t.Fatalf("config IDs = %v, want %v", explanation.ConfigIDs, tt.want)
+			}
+			for _, candidate := range explanation.Candidates {
+				if want, ok := tt.scores[candidate.ConfigID]; ok && candidate.Score != want {
+					t.Errorf("score of %s = %d, want %d", candidate.ConfigID, candidate.Score, want)
+				}
+			}
+		})
+	}
+}
+
+// TestLookupDoesNotBlockOnWriters matches while the filters and the clock are
+// changed concurrently; run with -race.
+func TestLookupDoesNotBlockOnWriters(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(dir+"/configs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	audit, err := NewAuditLog(dir + "/audit.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := NewConfigService(dir+"/configs", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs.audit = audit
+	if err := configs.AddConfig(models.Config{ID: "A", Actions: []models.Action{}}); err != nil {
+		t.Fatal(err)
+	}
+	switches, err := NewKillSwitchService(dir+"/killswitches.yaml", NormalizeOptions{}, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestSpecificService(t, NormalizeOptions{}, models.SpecificConfig{ID: "s", DataSource: models.DataSource{
+		Hosts: map[string]models.Mapping{"example.com": ids("A")},
+	}})
+	s.AddConfigFilter(switches)
+	s.AddConfigFilter(configs)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			switches.Toggle("alice", models.KillSwitchToggle{Scope: KillScopeConfig, Target: "A", Killed: i%2 == 0})
+			configs.SetRollout("alice", "A", i%100)
+			s.SetClock(func() time.Time { return time.Unix(int64(i), 0) })
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			switches.Toggle("alice", models.KillSwitchToggle{Scope: KillScopeConfig, Target: "A"})
+			configs.SetRollout("alice", "A", 100)
+			if got := s.GetMatchingConfigs(MatchRequest{Host: "example.com"}).ConfigIDs; len(got) != 1 {
+				t.Fatalf("config IDs = %v, want [A]", got)
+			}
+			return
+		default:
+			s.GetMatchingConfigs(MatchRequest{Host: "example.com", VisitorID: "v"})
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"net"
+	"path"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeOptions controls how hosts are canonicalized before matching.
+// Default ports (80 and 443) are always removed.
+type NormalizeOptions struct {
+	StripWWW  bool // treat www.example.com as example.com
+	StripPort bool // drop every port, not only the default ones
+}
+
+// NormalizeHost lowercases a host, converts Unicode labels to punycode and
+// applies the port and www options. Wildcard labels are kept as they are.
+func NormalizeHost(host string, opts NormalizeOptions) string {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return ""
+	}
+
+	name, port := host, ""
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		name, port = h, p
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if label == "*" {
+			continue
+		}
+		if ascii, err := idna.Lookup.ToASCII(label); err == nil {
+			labels[i] = ascii
+		}
+	}
+	name = strings.Join(labels, ".")
+
+	if opts.StripWWW {
+		name = strings.TrimPrefix(name, "www.")
+	}
+	if port == "" || port == "80" || port == "443" || opts.StripPort {
+		return name
+	}
+	return net.JoinHostPort(name, port)
+}
+
+// NormalizePath canonicalizes a url path: percent-encodings of unreserved
+// characters are decoded and the rest uppercased, duplicate and trailing
+// slashes and dot segments are removed. Any query or fragment is dropped.
+func NormalizePath(p string) string {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return ""
+	}
+
+	return path.Clean("/" + normalizePercentEncoding(p))
+}
+
+// normalizeHostKey applies NormalizeHost to a host key; regex keys are left
+// untouched since they are written against the canonical form.
+func normalizeHostKey(key string, opts NormalizeOptions) string {
+	if strings.HasPrefix(key, regexPrefix) {
+		return key
+	}
+	return NormalizeHost(key, opts)
+}
+
+// normalizePathKey applies NormalizePath to a url key, except for regexes.
+func normalizePathKey(key string) string {
+	if strings.HasPrefix(key, regexPrefix) {
+		return key
+	}
+	return NormalizePath(key)
+}
+
+// normalizePercentEncoding rewrites %xx escapes so equivalent spellings of a
+// path compare equal.
+func normalizePercentEncoding(p string) string {
+	if !strings.Contains(p, "%") {
+		return p
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' || i+2 >= len(p) || !isHex(p[i+1]) || !isHex(p[i+2]) {
+			b.WriteByte(p[i])
+			continue
+		}
+
+		c := unhex(p[i+1])<<4 | unhex(p[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(p[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character.
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
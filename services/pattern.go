@@ -0,0 +1,238 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix marks a datasource key as an anchored regular expression.
+const regexPrefix = "re:"
+
+// patternKind describes how a datasource key is compared against a request value.
+type patternKind int
+
+const (
+	patternExact patternKind = iota // plain key, compared verbatim
+	patternParam                    // url with named parameters, e.g. /products/:id
+	patternGlob                     // wildcards, e.g. *.example.com or /products/*
+	patternRegex                    // opt-in regular expression, e.g. re:^/p/[0-9]+$
+)
+
+// pattern is a compiled datasource key.
+type pattern struct {
+	raw      string
+	kind     patternKind
+	segments []string       // host labels or url segments for param/glob patterns
+	re       *regexp.Regexp // only set for regex patterns
+	sep      string         // "." for hosts, "/" for urls, empty for pages
+
+	// broadness is 0 for exact keys and grows with the number of values the
+	// pattern can match. It is used to prefer specific patterns on equal score.
+	broadness int
+}
+
+// match reports whether value matches the pattern. Named url parameters are
+// returned in params when the pattern declares any.
+func (p *pattern) match(value string) (bool, map[string]string) {
+	switch p.kind {
+	case patternExact:
+		return p.raw == value, nil
+	case patternRegex:
+		return p.re.MatchString(value), nil
+	}
+
+	if p.sep == "." {
+		return matchHostLabels(p.segments, strings.Split(value, ".")), nil
+	}
+	if p.sep == "/" {
+		return matchPathSegments(p.segments, splitPath(value))
+	}
+
+	ok, _ := path.Match(p.raw, value)
+	return ok, nil
+}
+
+// compileHostPattern compiles a host key. A leading "*" label matches one or
+// more labels ("*.example.com" matches "www.example.com" and
+// "a.b.example.com"), any other "*" label matches exactly one label.
+func compileHostPattern(raw string) (*pattern, error) {
+	if p, ok, err := compileRegexPattern(raw); ok {
+		return p, err
+	}
+	if !strings.Contains(raw, "*") {
+		return &pattern{raw: raw, kind: patternExact}, nil
+	}
+
+	labels := strings.Split(raw, ".")
+	literal, wildcards := 0, 0
+	for _, label := range labels {
+		if label == "*" {
+			wildcards++
+			continue
+		}
+		if strings.Contains(label, "*") {
+			return nil, fmt.Errorf("invalid host pattern '%s': wildcard must be a whole label", raw)
+		}
+		literal += len(label)
+	}
+
+	return &pattern{
+		raw:       raw,
+		kind:      patternGlob,
+		segments:  labels,
+		sep:       ".",
+		broadness: globBroadness(literal, wildcards),
+	}, nil
+}
+
+// compileURLPattern compiles a url key. Segments starting with ":" are named
+// parameters, "*" matches exactly one segment and a trailing "**" matches any
+// number of remaining segments.
+func compileURLPattern(raw string) (*pattern, error) {
+	if p, ok, err := compileRegexPattern(raw); ok {
+		return p, err
+	}
+	if !strings.ContainsAny(raw, ":*") {
+		return &pattern{raw: raw, kind: patternExact}, nil
+	}
+
+	segments := splitPath(raw)
+	kind := patternParam
+	literal, wildcards := 0, 0
+	for i, segment := range segments {
+		switch {
+		case segment == "**":
+			if i != len(segments)-1 {
+				return nil, fmt.Errorf("invalid url pattern '%s': '**' is only allowed as the last segment", raw)
+			}
+			kind = patternGlob
+			wildcards += 2
+		case segment == "*":
+			kind = patternGlob
+			wildcards++
+		case strings.HasPrefix(segment, ":"):
+			if len(segment) == 1 {
+				return nil, fmt.Errorf("invalid url pattern '%s': parameter name is missing", raw)
+			}
+			wildcards++
+		case strings.Contains(segment, "*"):
+			return nil, fmt.Errorf("invalid url pattern '%s': wildcard must be a whole segment", raw)
+		default:
+			literal += len(segment)
+		}
+	}
+
+	broadness := globBroadness(literal, wildcards)
+	if kind == patternParam {
+		broadness -= 1000
+	}
+
+	return &pattern{raw: raw, kind: kind, segments: segments, sep: "/", broadness: broadness}, nil
+}
+
+// compilePagePattern compiles a page key. Pages support shell-style globs
+// ("checkout-*") in addition to exact names and regular expressions.
+func compilePagePattern(raw string) (*pattern, error) {
+	if p, ok, err := compileRegexPattern(raw); ok {
+		return p, err
+	}
+	if !strings.ContainsAny(raw, "*?[") {
+		return &pattern{raw: raw, kind: patternExact}, nil
+	}
+	if _, err := path.Match(raw, ""); err != nil {
+		return nil, fmt.Errorf("invalid page pattern '%s': %w", raw, err)
+	}
+
+	literal := len(raw) - strings.Count(raw, "*") - strings.Count(raw, "?")
+	return &pattern{raw: raw, kind: patternGlob, broadness: globBroadness(literal, 1)}, nil
+}
+
+// compileRegexPattern handles the "re:" prefix shared by every key type. The
+// expression is always anchored so it has to match the whole value. It is
+// compiled on its own first, so an expression such as "a)|(b" can not close
+// the group it is wrapped in.
+func compileRegexPattern(raw string) (*pattern, bool, error) {
+	if !strings.HasPrefix(raw, regexPrefix) {
+		return nil, false, nil
+	}
+
+	expr := strings.TrimPrefix(raw, regexPrefix)
+	if _, err := regexp.Compile(expr); err != nil {
+		return nil, true, fmt.Errorf("invalid regex pattern '%s': %w", raw, err)
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid regex pattern '%s': %w", raw, err)
+	}
+
+	return &pattern{raw: raw, kind: patternRegex, re: re, broadness: 3000}, true, nil
+}
+
+// globBroadness ranks wildcard patterns: more literal characters make a
+// pattern more specific, more wildcards make it broader.
+func globBroadness(literal, wildcards int) int {
+	if literal > 900 {
+		literal = 900
+	}
+	return 2000 - literal + wildcards
+}
+
+func matchHostLabels(pattern, labels []string) bool {
+	if len(pattern) > 0 && pattern[0] == "*" {
+		rest := pattern[1:]
+		if len(labels) <= len(rest) {
+			return false
+		}
+		return matchHostLabels(rest, labels[len(labels)-len(rest):])
+	}
+
+	if len(pattern) != len(labels) {
+		return false
+	}
+	for i, label := range pattern {
+		if label != "*" && label != labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPathSegments(pattern, segments []string) (bool, map[string]string) {
+	var params map[string]string
+
+	for i, p := range pattern {
+		if p == "**" {
+			return true, params
+		}
+		if i >= len(segments) {
+			return false, nil
+		}
+
+		switch {
+		case p == "*":
+		case strings.HasPrefix(p, ":"):
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[p[1:]] = segments[i]
+		case p != segments[i]:
+			return false, nil
+		}
+	}
+
+	if len(pattern) != len(segments) {
+		return false, nil
+	}
+	return true, params
+}
+
+// splitPath splits a url path into its non-empty segments.
+func splitPath(p string) []string {
+	trimmed := strings.Trim(p, "/")
+	if trimmed == "" {
+		return nil
+	}
+	return strings.Split(trimmed, "/")
+}
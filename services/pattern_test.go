@@ -0,0 +1,30 @@
+package services
+
+import "testing"
+
+func TestRegexPatternAnchoring(t *testing.T) {
+	cases := []struct {
+		raw, value string
+		want       bool
+	}{
+		{`re:/price/[0-9]+\$`, "/price/10$", true},
+		{`re:/price/[0-9]+\$`, "/price/10", false},
+		{`re:^/p/[0-9]+$`, "/p/42", true},
+		{`re:/p/[0-9]+`, "/p/42/reviews", false},
+		{`re:a|b`, "ab", false},
+		{`re:a|b`, "b", true},
+	}
+	for _, c := range cases {
+		p, ok, err := compileRegexPattern(c.raw)
+		if !ok || err != nil {
+			t.Fatalf("%s: %v", c.raw, err)
+		}
+		if got, _ := p.match(c.value); got != c.want {
+			t.Errorf("%s matches %s: %v, want %v", c.raw, c.value, got, c.want)
+		}
+	}
+
+	if _, _, err := compileRegexPattern(`re:a)|(b`); err == nil {
+		t.Error("an expression closing the anchoring group was accepted")
+	}
+}
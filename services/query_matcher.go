@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// queryPrefixOperator marks a query value that only has to start with the
+// given text, e.g. "prefix:spring".
+const queryPrefixOperator = "prefix:"
+
+// queryMatcher checks a single query parameter. The expected value is an
+// exact value by default; "" or "*" only require the parameter to be present,
+// "prefix:" matches the start of the value and "re:" an anchored regex.
+type queryMatcher struct {
+	name   string
+	raw    string
+	prefix string
+	exact  string
+	re     *regexp.Regexp
+}
+
+func compileQueryMatchers(query map[string]string) ([]queryMatcher, error) {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matchers := make([]queryMatcher, 0, len(names))
+	for _, name := range names {
+		raw := query[name]
+		matcher := queryMatcher{name: name, raw: raw}
+
+		switch {
+		case raw == "" || raw == "*":
+		case strings.HasPrefix(raw, queryPrefixOperator):
+			matcher.prefix = strings.TrimPrefix(raw, queryPrefixOperator)
+		case strings.HasPrefix(raw, regexPrefix):
+			p, _, err := compileRegexPattern(raw)
+			if err != nil {
+				return nil, fmt.Errorf("query parameter '%s': %w", name, err)
+			}
+			matcher.re = p.re
+		default:
+			matcher.exact = raw
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// match returns the first value of the parameter that satisfies the matcher.
+func (m queryMatcher) match(query url.Values) (string, bool) {
+	values, present := query[m.name]
+	if !present {
+		return "", false
+	}
+
+	for _, value := range values {
+		switch {
+		case m.re != nil:
+			if m.re.MatchString(value) {
+				return value, true
+			}
+		case m.prefix != "":
+			if strings.HasPrefix(value, m.prefix) {
+				return value, true
+			}
+		case m.exact != "":
+			if value == m.exact {
+				return value, true
+			}
+		default:
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// matchQuery checks every matcher and returns the values that satisfied
+// them, keyed by parameter name.
+func matchQuery(matchers []queryMatcher, query url.Values) (map[string]string, bool) {
+	if len(matchers) == 0 {
+		return nil, true
+	}
+
+	matched := make(map[string]string, len(matchers))
+	for _, matcher := range matchers {
+		value, ok := matcher.match(query)
+		if !ok {
+			return nil, false
+		}
+		matched[matcher.name] = value
+	}
+	return matched, true
+}
+
+// queryString renders matchers for explanations, e.g.
+// "query.utm_campaign=prefix:spring".
+func queryString(matchers []queryMatcher) []string {
+	parts := make([]string, len(matchers))
+	for i, matcher := range matchers {
+		parts[i] = "query." + matcher.name + "=" + matcher.raw
+	}
+	return parts
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"ssd-assignment-api/models"
+)
+
+// RepairFileNames finds the single-document files of a directory whose name
+// does not match their ID and, with apply, renames them to <id> with their
+// extension kept. Files are left alone, and listed with the reason, when the
+// new name is taken or the ID can not be used as a file name.
+func RepairFileNames(dir string, apply bool) ([]models.FileRename, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("directory %s could not be read: %w", dir, err)
+	}
+
+	existing := make(map[string]bool, len(files))
+	for _, file := range files {
+		existing[file.Name()] = true
+	}
+
+	var renames []models.FileRename
+	claimed := map[string]string{}
+	for _, file := range files {
+		if file.IsDir() || !isDocumentFile(file.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		ext := filepath.Ext(path)
+		docs, err := readDocuments(path)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return renames, fmt.Errorf("%s could not be read: %w", path, err)
+		}
+
+		var doc struct {
+			ID string `yaml:"id"`
+		}
+		if err == nil && len(docs) == 1 && docs[0] != nil {
+			err = docs[0].Decode(&doc)
+		}
+		rename := models.FileRename{From: path}
+		switch {
+		case err != nil:
+			rename.Skipped = "file could not be parsed: " + err.Error()
+		case len(docs) != 1 || doc.ID == "" || doc.ID+ext == file.Name():
+			// Multi-document files are not named after an ID
+			continue
+		case !isFileName(doc.ID):
+			rename.Skipped = fmt.Sprintf("id '%s' can not be used as a file name", doc.ID)
+		case existing[doc.ID+ext]:
+			rename.Skipped = fmt.Sprintf("%s%s already exists", doc.ID, ext)
+		case claimed[doc.ID] != "":
+			rename.Skipped = fmt.Sprintf("id '%s' is also defined in %s", doc.ID, claimed[doc.ID])
+		default:
+			rename.To = filepath.Join(dir, doc.ID+ext)
+			claimed[doc.ID] = path
+		}
+
+		if apply && rename.Skipped == "" {
+			if err := os.Rename(rename.From, rename.To); err != nil {
+				return renames, fmt.Errorf("%s could not be renamed: %w", path, err)
+			}
+		}
+		renames = append(renames, rename)
+	}
+	return renames, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"path/filepath"
+	"ssd-assignment-api/models"
+	"strings"
+)
+
+// Resolver turns a match into the configurations it refers to.
+type Resolver struct {
+	configs   *ConfigService
+	specifics *SpecificConfigService
+}
+
+func NewResolver(configs *ConfigService, specifics *SpecificConfigService) *Resolver {
+	return &Resolver{configs: configs, specifics: specifics}
+}
+
+// Resolve matches the request and loads every matched configuration in
+// ranking order. IDs that do not refer to a stored configuration are
+// reported in Missing instead of failing the whole request.
+func (r *Resolver) Resolve(req MatchRequest) models.ResolveResult {
+	match := r.specifics.GetMatchingConfigs(req)
+
+	result := models.ResolveResult{
+		ConfigIDs:   match.ConfigIDs,
+		Configs:     []models.Config{},
+		Experiments: match.Experiments,
+		Excluded:    match.Excluded,
+		Fallback:    match.Fallback,
+		Reason:      match.Reason,
+	}
+	for _, ref := range match.ConfigIDs {
+		config, err := r.configs.GetConfigByID(ConfigIDFromRef(ref))
+		if err != nil {
+			result.Missing = append(result.Missing, ref)
+			continue
+		}
+		result.Configs = append(result.Configs, config)
+	}
+
+	return result
+}
+
+// ConfigIDFromRef maps a reference used in specific configs, which may be a
+// file name such as "A.yaml", to the configuration ID "A".
+func ConfigIDFromRef(ref string) string {
+	switch ext := filepath.Ext(ref); ext {
+	case ".yaml", ".yml":
+		return strings.TrimSuffix(ref, ext)
+	}
+	return ref
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"ssd-assignment-api/models"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isResource reports whether a document is in the resource form, which is
+// recognized by its apiVersion key.
+func isResource(doc *yaml.Node) bool {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "apiVersion" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeConfigDocument decodes a configuration in the flat or the resource
+// form.
+func decodeConfigDocument(doc *yaml.Node) (models.Config, error) {
+	if !isResource(doc) {
+		var config models.Config
+		err := doc.Decode(&config)
+		return config, err
+	}
+
+	var resource models.ConfigResource
+	if err := doc.Decode(&resource); err != nil {
+		return models.Config{}, err
+	}
+	return resource.Config()
+}
+
+// decodeSpecificDocument decodes a specific config in the flat or the
+// resource form.
+func decodeSpecificDocument(doc *yaml.Node) (models.SpecificConfig, error) {
+	if !isResource(doc) {
+		var config models.SpecificConfig
+		err := doc.Decode(&config)
+		return config, err
+	}
+
+	var resource models.SpecificConfigResource
+	if err := doc.Decode(&resource); err != nil {
+		return models.SpecificConfig{}, err
+	}
+	return resource.SpecificConfig()
+}
+
+// touch sets the timestamps of a revision that replaces previous. A new
+// revision keeps the creation time of the one it replaces unless it brings
+// its own, as an imported config does.
+func touch(meta *models.ResourceMeta, previous models.ResourceMeta, exists bool) {
+	now := time.Now().UTC().Truncate(time.Second)
+	if meta.CreatedAt == nil {
+		if exists {
+			meta.CreatedAt = previous.CreatedAt
+		} else {
+			meta.CreatedAt = &now
+		}
+	}
+	meta.UpdatedAt = &now
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"os"
+	"strings"
+)
+
+// Roles a user can hold. Every user is an editor; publishers and approvers
+// are listed by username in ROLE_PUBLISHERS and ROLE_APPROVERS
+// (comma-separated). When a variable is unset nobody holds that role.
+const (
+	RoleEditor    = "editor"
+	RolePublisher = "publisher"
+	RoleApprover  = "approver"
+)
+
+var roleVariables = map[string]string{
+	RolePublisher: "ROLE_PUBLISHERS",
+	RoleApprover:  "ROLE_APPROVERS",
+}
+
+// RolesFor returns the roles granted to a user.
+func RolesFor(username string) []string {
+	roles := []string{RoleEditor}
+	for _, role := range []string{RolePublisher, RoleApprover} {
+		if containsString(splitList(os.Getenv(roleVariables[role])), username) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func HasRole(roles []string, role string) bool {
+	return containsString(roles, role)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"ssd-assignment-api/models"
+	"time"
+)
+
+// Clock returns the current time. Services take one so schedules can be
+// evaluated at any instant.
+type Clock func() time.Time
+
+// scheduleLayouts are the local time formats accepted besides RFC 3339.
+var scheduleLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// window is a parsed Schedule. Zero bounds are open.
+type window struct {
+	from  time.Time
+	until time.Time
+}
+
+// active reports whether at falls inside the window; from is inclusive and
+// until exclusive.
+func (w window) active(at time.Time) bool {
+	if !w.from.IsZero() && at.Before(w.from) {
+		return false
+	}
+	if !w.until.IsZero() && !at.Before(w.until) {
+		return false
+	}
+	return true
+}
+
+func parseSchedule(schedule models.Schedule) (window, error) {
+	location := time.UTC
+	if schedule.Timezone != "" {
+		loc, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return window{}, fmt.Errorf("invalid timezone '%s': %w", schedule.Timezone, err)
+		}
+		location = loc
+	}
+
+	from, err := parseScheduleTime(schedule.ActiveFrom, location)
+	if err != nil {
+		return window{}, fmt.Errorf("invalid activeFrom: %w", err)
+	}
+	until, err := parseScheduleTime(schedule.ActiveUntil, location)
+	if err != nil {
+		return window{}, fmt.Errorf("invalid activeUntil: %w", err)
+	}
+	if !from.IsZero() && !until.IsZero() && !until.After(from) {
+		return window{}, fmt.Errorf("activeUntil must be after activeFrom")
+	}
+
+	return window{from: from, until: until}, nil
+}
+
+func parseScheduleTime(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range scheduleLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a supported time", value)
+}
+
+// transitions appends the future bounds of w to list.
+func (w window) transitions(now time.Time, base models.ScheduleTransition, list []models.ScheduleTransition) []models.ScheduleTransition {
+	if !w.from.IsZero() && w.from.After(now) {
+		t := base
+		t.At, t.Event = w.from, "activate"
+		list = append(list, t)
+	}
+	if !w.until.IsZero() && w.until.After(now) {
+		t := base
+		t.At, t.Event = w.until, "deactivate"
+		list = append(list, t)
+	}
+	return list
+}
+
+// transitions appends the future schedule bounds of a compiled mapping.
+func (m compiledMapping) transitions(now time.Time, source, kind, rule string, list []models.ScheduleTransition) []models.ScheduleTransition {
+	if m.window == nil {
+		return list
+	}
+	base := models.ScheduleTransition{
+		Kind:           kind,
+		SpecificConfig: source,
+		Rule:           rule,
+		Pattern:        m.pattern.raw,
+		ConfigIDs:      m.ids,
+	}
+	return m.window.transitions(now, base, list)
+}
+
+// UpcomingTransitions lists every future activation and deactivation of
+// configurations and specific config mappings, earliest first. Transitions
+// at the same instant are ordered by config ID, specific config, rule and
+// pattern, so the list is the same on every call.
+func UpcomingTransitions(configs *ConfigService, specifics *SpecificConfigService, now time.Time) []models.ScheduleTransition {
+	transitions := append(configs.scheduleTransitions(now), specifics.scheduleTransitions(now)...)
+	sort.SliceStable(transitions, func(i, j int) bool {
+		a, b := transitions[i], transitions[j]
+		if !a.At.Equal(b.At) {
+			return a.At.Before(b.At)
+		}
+		if a.ConfigID != b.ConfigID {
+			return a.ConfigID < b.ConfigID
+		}
+		if a.SpecificConfig != b.SpecificConfig {
+			return a.SpecificConfig < b.SpecificConfig
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Pattern < b.Pattern
+	})
+	return transitions
+}
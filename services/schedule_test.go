@@ -0,0 +1,57 @@
+package services
+
+import (
+	"ssd-assignment-api/models"
+	"testing"
+	"time"
+)
+
+func TestScheduleTimezoneIsValidated(t *testing.T) {
+	if _, err := scheduleWindow(models.Config{ID: "A", Schedule: models.Schedule{Timezone: "Mars/Olympus"}}); err == nil {
+		t.Error("a schedule with only an invalid timezone was accepted")
+	}
+	if _, err := scheduleWindow(models.Config{ID: "A", Schedule: models.Schedule{Timezone: "Europe/Istanbul"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpcomingTransitionsOrder(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := models.Schedule{ActiveFrom: "2026-02-01T00:00:00Z"}
+	configs := &ConfigService{windows: map[string]window{}}
+	for _, id := range []string{"C", "A", "B"} {
+		w, err := scheduleWindow(models.Config{ID: id, Schedule: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs.windows[id] = *w
+	}
+	specifics := newTestSpecificService(t, NormalizeOptions{}, models.SpecificConfig{ID: "s", DataSource: models.DataSource{
+		Hosts: map[string]models.Mapping{"example.com": {IDs: []string{"A"}, Schedule: at}},
+		URLs:  map[string]models.Mapping{"example.com": {IDs: []string{"A"}, Schedule: at}},
+		Pages: map[string]models.Mapping{"example.com": {IDs: []string{"A"}, Schedule: at}},
+	}})
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		var got []string
+		for _, transition := range UpcomingTransitions(configs, specifics, now) {
+			got = append(got, transition.ConfigID+"/"+transition.Rule)
+		}
+		if want == nil {
+			want = got
+			if len(want) != 6 || want[0] != "/host" || want[3] != "A/" || want[5] != "C/" {
+				t.Fatalf("transitions in order %v", want)
+			}
+			continue
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatalf("order changed between calls: %v, then %v", want, got)
+			}
+		}
+	}
+}
@@ -3,26 +3,40 @@ package services
 import (
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"sort"
 	"ssd-assignment-api/models"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 type SpecificConfigService struct {
-	configs map[string]models.SpecificConfig
-	mutex   sync.Mutex
-	yamlDir string
+	configs   map[string]models.SpecificConfig
+	compiled  map[string]*compiledSpecificConfig
+	index     atomic.Pointer[matchIndex]
+	normalize NormalizeOptions
+	filters   []ConfigFilter
+	assigner  VariantAssigner
+	clock     Clock
+	load      loadRecorder
+	sources   sourceIndex // the file and document every config was loaded from
+	labels    labelIndex
+	mutex     sync.Mutex
+	yamlDir   string
 }
 
-func NewSpecificConfigService(yamlDir string) (*SpecificConfigService, error) {
+// NewSpecificConfigService loads the YAML directory; in lenient mode files
+// that fail to load are skipped and listed in the load report.
+func NewSpecificConfigService(yamlDir string, opts LoadOptions) (*SpecificConfigService, error) {
 	service := &SpecificConfigService{
-		configs: make(map[string]models.SpecificConfig),
-		yamlDir: yamlDir,
+		configs:  make(map[string]models.SpecificConfig),
+		compiled: make(map[string]*compiledSpecificConfig),
+		clock:    time.Now,
+		load:     newLoadRecorder(opts),
+		sources:  make(sourceIndex),
+		labels:   make(labelIndex),
+		yamlDir:  yamlDir,
 	}
 
 	if err := service.loadConfigsFromYAML(); err != nil {
@@ -32,114 +46,316 @@ func NewSpecificConfigService(yamlDir string) (*SpecificConfigService, error) {
 	return service, nil
 }
 
-func (s *SpecificConfigService) GetMatchingConfigs(host, url, page string) ([]string, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// GetMatchingConfigs returns the IDs of all configs matching the request,
+// best match first. When nothing matches the fallback IDs are returned, and
+// when there is no fallback either the list is empty and Reason says why.
+func (s *SpecificConfigService) GetMatchingConfigs(req MatchRequest) models.MatchResult {
+	explanation := s.explain(req)
+	return models.MatchResult{
+		ConfigIDs:   explanation.ConfigIDs,
+		Experiments: explanation.Experiments,
+		Excluded:    explanation.Excluded,
+		Fallback:    explanation.Fallback,
+		Reason:      explanation.Reason,
+	}
+}
 
-	priorityMap := make(map[string]int)
-	foundMatches := false
+// ExplainMatch returns the ranking GetMatchingConfigs would produce together
+// with every contribution and tie-break behind it.
+func (s *SpecificConfigService) ExplainMatch(req MatchRequest) models.MatchExplanation {
+	return s.explain(req)
+}
 
-	for _, config := range s.configs {
-		if host != "" {
-			if ids, exists := config.DataSource.Hosts[host]; exists {
-				foundMatches = true
-				for _, id := range ids {
-					priorityMap[id] += 3
-				}
-			}
-		}
+// explain reads from the current match index without locking, so lookups
+// never wait on writers. The clock and the filters are part of the index.
+func (s *SpecificConfigService) explain(req MatchRequest) models.MatchExplanation {
+	index := s.index.Load()
+	req.Host = NormalizeHost(req.Host, index.normalize)
+	req.URL = NormalizePath(req.URL)
+	if req.At.IsZero() {
+		req.At = index.clock()
+	}
 
-		if url != "" {
-			if ids, exists := config.DataSource.URLs[url]; exists {
-				foundMatches = true
-				for _, id := range ids {
-					priorityMap[id] += 2
-				}
-			}
+	var hosts, urls, pages []*indexedMapping
+	if req.Host != "" {
+		hosts = index.hosts.lookup(req.Host)
+	}
+	if req.URL != "" {
+		urls = index.urls.lookup(req.URL)
+	}
+	if req.Page != "" {
+		pages = index.pages.lookup(req.Page)
+	}
+	hosts = filterActive(filterQuery(hosts, req.Query), req.At)
+	urls = filterActive(filterQuery(urls, req.Query), req.At)
+	pages = filterActive(filterQuery(pages, req.Query), req.At)
+	rules := filterActive(index.matchRules(req), req.At)
+
+	var explanation models.MatchExplanation
+	if threshold, stopped := stopPriority(hosts, urls, pages, rules); stopped {
+		var dropped []*indexedMapping
+		explanation.StoppedBelow = &threshold
+		hosts, dropped = dropBelow(hosts, threshold)
+		explanation.Stopped = append(explanation.Stopped, describeMappings(dropped, "host")...)
+		urls, dropped = dropBelow(urls, threshold)
+		explanation.Stopped = append(explanation.Stopped, describeMappings(dropped, "url")...)
+		pages, dropped = dropBelow(pages, threshold)
+		explanation.Stopped = append(explanation.Stopped, describeMappings(dropped, "page")...)
+		rules, dropped = dropBelow(rules, threshold)
+		explanation.Stopped = append(explanation.Stopped, describeMappings(dropped, "rule")...)
+	}
+
+	candidates := make(map[string]*matchCandidate)
+	scoreMappings(candidates, hosts, "host", req.Host, req.Query)
+	scoreMappings(candidates, urls, "url", req.URL, req.Query)
+	scoreMappings(candidates, pages, "page", req.Page, req.Query)
+	scoreMappings(candidates, rules, "rule", "", req.Query)
+
+	// Exclusions veto an ID regardless of its score, filters such as
+	// schedules decide whether the config behind it may be served
+	excluded := index.exclusions(req)
+	matched := len(candidates) > 0
+	for id := range candidates {
+		if _, vetoed := excluded[id]; vetoed {
+			delete(candidates, id)
+		} else if reason := index.filter(id, req); reason != "" {
+			explanation.Filtered = append(explanation.Filtered, models.MatchFiltered{ConfigID: id, Reason: reason})
+			delete(candidates, id)
 		}
+	}
+
+	ranked := rankCandidates(candidates)
+	configIDs := make([]string, len(ranked))
+	for i, candidate := range ranked {
+		configIDs[i] = candidate.ConfigID
+	}
 
-		if page != "" {
-			if ids, exists := config.DataSource.Pages[page]; exists {
-				foundMatches = true
-				for _, id := range ids {
-					priorityMap[id] += 1
-				}
+	explanation.Host = req.Host
+	explanation.URL = req.URL
+	explanation.Page = req.Page
+	explanation.Query = req.Query
+	explanation.Device = req.Device
+	explanation.Language = req.Language()
+	explanation.ConfigIDs = configIDs
+	explanation.Candidates = ranked
+
+	explanation.Excluded = flattenExclusions(excluded)
+
+	if len(configIDs) == 0 {
+		fallbackIDs, reason := index.fallback(req.Host)
+		explanation.ConfigIDs = []string{}
+		for _, id := range fallbackIDs {
+			if _, vetoed := excluded[id]; vetoed {
+				continue
+			}
+			if filterReason := index.filter(id, req); filterReason != "" {
+				explanation.Filtered = append(explanation.Filtered, models.MatchFiltered{ConfigID: id, Reason: filterReason})
+				continue
 			}
+			explanation.ConfigIDs = append(explanation.ConfigIDs, id)
+		}
+		explanation.Fallback = len(explanation.ConfigIDs) > 0
+		explanation.Reason = reason
+		if matched && len(fallbackIDs) == 0 {
+			explanation.Reason = "every matched config is excluded or filtered and no fallback is configured"
+		}
+		if len(fallbackIDs) > 0 && !explanation.Fallback {
+			explanation.Reason = "no mapping matched and every fallback config is excluded or filtered"
 		}
 	}
 
-	if !foundMatches {
-		return nil, errors.New("no matching configurations found")
+	if index.assigner != nil {
+		assignExperiments(&explanation, index, excluded, req)
 	}
-
-	return s.sortConfigsByPriority(priorityMap), nil
+	return explanation
 }
 
-func (s *SpecificConfigService) sortConfigsByPriority(priorityMap map[string]int) []string {
-	type configPriority struct {
-		ID    string
-		Score int
+// assignExperiments replaces experiment references in the ranked IDs with the
+// IDs of the assigned variant. Variant IDs are subject to the same exclusions
+// and filters as mapped IDs and keep the rank of the reference.
+func assignExperiments(explanation *models.MatchExplanation, index *matchIndex, excluded map[string][]models.MatchExclusion, req MatchRequest) {
+	seen := make(map[string]bool, len(explanation.ConfigIDs))
+	configIDs := make([]string, 0, len(explanation.ConfigIDs))
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			configIDs = append(configIDs, id)
+		}
 	}
 
-	var sorted []configPriority
-	for id, score := range priorityMap {
-		sorted = append(sorted, configPriority{id, score})
+	for _, ref := range explanation.ConfigIDs {
+		assignment, ok := index.assigner.Assign(ref, req)
+		if !ok {
+			add(ref)
+			continue
+		}
+
+		explanation.Experiments = append(explanation.Experiments, assignment)
+		for _, id := range assignment.ConfigIDs {
+			if _, vetoed := excluded[id]; vetoed {
+				continue
+			}
+			if reason := index.filter(id, req); reason != "" {
+				explanation.Filtered = append(explanation.Filtered, models.MatchFiltered{ConfigID: id, Reason: reason})
+				continue
+			}
+			add(id)
+		}
 	}
+	explanation.ConfigIDs = configIDs
+}
 
-	sort.Slice(sorted, func(i, j int) bool {
-		if sorted[i].Score == sorted[j].Score {
-			return sorted[i].ID < sorted[j].ID
+func (s *SpecificConfigService) loadConfigsFromYAML() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.load.loadDocuments("specific", s.yamlDir, func(src source, doc *yaml.Node, single bool) error {
+		config, compiled, err := s.decodeSpecificConfig(doc)
+		if err != nil {
+			return err
+		}
+		if err := s.load.checkID("specific", src, single, &config.ID); err != nil {
+			return err
 		}
-		return sorted[i].Score > sorted[j].Score
+
+		src.resource = isResource(doc)
+		s.putConfig(config.ID, config)
+		s.compiled[config.ID] = compiled
+		s.sources[config.ID] = src
+		return nil
 	})
+	if err != nil {
+		return err
+	}
 
-	result := make([]string, len(sorted))
-	for i, item := range sorted {
-		result[i] = item.ID
+	s.rebuildIndex()
+	return nil
+}
+
+// decodeSpecificConfig decodes a specific config document in either form and
+// compiles it. Callers must hold the mutex.
+func (s *SpecificConfigService) decodeSpecificConfig(doc *yaml.Node) (models.SpecificConfig, *compiledSpecificConfig, error) {
+	config, err := decodeSpecificDocument(doc)
+	if err != nil {
+		return models.SpecificConfig{}, nil, fmt.Errorf("document could not be parsed: %w", err)
 	}
 
-	return result
+	compiled, err := compileSpecificConfig(config, s.normalize)
+	if err != nil {
+		return models.SpecificConfig{}, nil, fmt.Errorf("invalid patterns: %w", err)
+	}
+	return config, compiled, nil
 }
 
-func (s *SpecificConfigService) loadConfigsFromYAML() error {
+// LoadReport returns the outcome of loading the YAML directory at startup.
+func (s *SpecificConfigService) LoadReport() models.LoadReport {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	files, err := os.ReadDir(s.yamlDir)
-	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
-	}
+	return s.load.report()
+}
 
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
-			continue
-		}
+// rebuildIndex builds a new match index from the compiled configs and
+// publishes it. Callers must hold the mutex.
+func (s *SpecificConfigService) rebuildIndex() {
+	index := buildMatchIndex(s.compiled, s.normalize, s.filters)
+	index.assigner = s.assigner
+	index.clock = s.clock
+	s.index.Store(index)
+}
 
-		filePath := filepath.Join(s.yamlDir, file.Name())
-		yamlData, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", filePath, err)
-		}
+// SetVariantAssigner sets the assigner experiment references in mappings are
+// resolved with. Without one they are returned as they are.
+func (s *SpecificConfigService) SetVariantAssigner(assigner VariantAssigner) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-		var config models.SpecificConfig
-		if err := yaml.Unmarshal(yamlData, &config); err != nil {
-			return fmt.Errorf("YAML parse hatası: %w", err)
+	s.assigner = assigner
+	s.rebuildIndex()
+}
+
+// AddConfigFilter registers a filter every matched config ID must pass.
+func (s *SpecificConfigService) AddConfigFilter(filter ConfigFilter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.filters = append(s.filters, filter)
+	s.rebuildIndex()
+}
+
+// SetClock replaces the clock schedules are evaluated with.
+func (s *SpecificConfigService) SetClock(clock Clock) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clock = clock
+	s.rebuildIndex()
+}
+
+// Now returns the current time of the service clock.
+func (s *SpecificConfigService) Now() time.Time {
+	return s.index.Load().clock()
+}
+
+func (s *SpecificConfigService) scheduleTransitions(now time.Time) []models.ScheduleTransition {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var transitions []models.ScheduleTransition
+	for _, id := range sortedKeys(s.compiled) {
+		compiled := s.compiled[id]
+		groups := map[string][]compiledMapping{"host": compiled.hosts, "url": compiled.urls, "page": compiled.pages}
+		for _, rule := range []string{"host", "url", "page"} {
+			for _, mapping := range groups[rule] {
+				transitions = mapping.transitions(now, id, "mapping", rule, transitions)
+			}
+		}
+		for _, rule := range compiled.rules {
+			transitions = rule.transitions(now, id, "rule", "rule", transitions)
 		}
+	}
+	return transitions
+}
+
+// SetNormalizeOptions changes how hosts are normalized and recompiles every
+// config with the new options.
+func (s *SpecificConfigService) SetNormalizeOptions(opts NormalizeOptions) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-		s.configs[config.ID] = config
+	compiled := make(map[string]*compiledSpecificConfig, len(s.configs))
+	for id, config := range s.configs {
+		c, err := compileSpecificConfig(config, opts)
+		if err != nil {
+			return fmt.Errorf("invalid patterns in %s: %w", id, err)
+		}
+		compiled[id] = c
 	}
+
+	s.normalize = opts
+	s.compiled = compiled
+	s.rebuildIndex()
 	return nil
 }
 
+// GetAllSpecificConfigs returns every specific config ordered by ID.
 func (s *SpecificConfigService) GetAllSpecificConfigs() ([]models.SpecificConfig, error) {
+	return s.FindSpecificConfigs(nil), nil
+}
+
+// FindSpecificConfigs returns the specific configs whose labels match
+// selector, ordered by ID.
+func (s *SpecificConfigService) FindSpecificConfigs(selector LabelSelector) []models.SpecificConfig {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	var configList []models.SpecificConfig
-	for _, config := range s.configs {
-		configList = append(configList, config)
+	configList := []models.SpecificConfig{}
+	for _, id := range s.labels.candidates(selector, sortedKeys(s.configs)) {
+		if config := s.configs[id]; selector.Matches(config.Labels) {
+			configList = append(configList, config)
+		}
 	}
-	return configList, nil
+	return configList
 }
 
 func (s *SpecificConfigService) GetSpecificConfigByID(id string) (models.SpecificConfig, error) {
@@ -153,6 +369,32 @@ func (s *SpecificConfigService) GetSpecificConfigByID(id string) (models.Specifi
 	return config, nil
 }
 
+// ValidateSpecificConfig compiles a config without storing it.
+func (s *SpecificConfigService) ValidateSpecificConfig(config models.SpecificConfig) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err := s.compile(config)
+	return err
+}
+
+// compile checks the labels of a config and compiles it. Callers must hold
+// the mutex.
+func (s *SpecificConfigService) compile(config models.SpecificConfig) (*compiledSpecificConfig, error) {
+	if err := validateLabels(config.Labels); err != nil {
+		return nil, err
+	}
+	return compileSpecificConfig(config, s.normalize)
+}
+
+// putConfig puts a config in memory and in the label index. Callers must
+// hold the mutex.
+func (s *SpecificConfigService) putConfig(id string, config models.SpecificConfig) {
+	s.labels.remove(id, s.configs[id].Labels)
+	s.configs[id] = config
+	s.labels.add(id, config.Labels)
+}
+
 func (s *SpecificConfigService) AddSpecificConfig(config models.SpecificConfig) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -160,13 +402,23 @@ func (s *SpecificConfigService) AddSpecificConfig(config models.SpecificConfig)
 	if _, exists := s.configs[config.ID]; exists {
 		return fmt.Errorf("config with ID '%s' already exists", config.ID)
 	}
+	if err := checkFileName(config.ID); err != nil {
+		return err
+	}
+	touch(&config.ResourceMeta, models.ResourceMeta{}, false)
 
-	filePath := fmt.Sprintf("%s/%s.yaml", s.yamlDir, config.ID)
-	if err := s.saveConfigToYAML(config, filePath); err != nil {
+	compiled, err := s.compile(config)
+	if err != nil {
+		return err
+	}
+
+	if err := s.saveConfigToYAML(config.ID, config); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	s.configs[config.ID] = config
+	s.putConfig(config.ID, config)
+	s.compiled[config.ID] = compiled
+	s.rebuildIndex()
 	return nil
 }
 
@@ -174,16 +426,24 @@ func (s *SpecificConfigService) UpdateSpecificConfig(id string, config models.Sp
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, exists := s.configs[id]; !exists {
+	previous, exists := s.configs[id]
+	if !exists {
 		return errors.New("specific config not found")
 	}
+	touch(&config.ResourceMeta, previous.ResourceMeta, true)
+
+	compiled, err := s.compile(config)
+	if err != nil {
+		return err
+	}
 
-	filePath := fmt.Sprintf("%s/%s.yaml", s.yamlDir, id)
-	if err := s.saveConfigToYAML(config, filePath); err != nil {
+	if err := s.saveConfigToYAML(id, config); err != nil {
 		return fmt.Errorf("failed to update config: %w", err)
 	}
 
-	s.configs[id] = config
+	s.putConfig(id, config)
+	s.compiled[id] = compiled
+	s.rebuildIndex()
 	return nil
 }
 
@@ -195,24 +455,39 @@ func (s *SpecificConfigService) DeleteSpecificConfig(id string) error {
 		return errors.New("specific config not found")
 	}
 
-	filePath := fmt.Sprintf("%s/%s.yaml", s.yamlDir, id)
-	if err := os.Remove(filePath); err != nil {
+	// Other documents of a multi-document file are kept
+	src, err := s.sources.get(s.yamlDir, id)
+	if err != nil {
+		return err
+	}
+	if _, err := removeDocument(src); err != nil {
 		return fmt.Errorf("failed to delete config file: %w", err)
 	}
+	s.sources.remove(id)
 
+	s.labels.remove(id, s.configs[id].Labels)
 	delete(s.configs, id)
+	delete(s.compiled, id)
+	s.rebuildIndex()
 	return nil
 }
 
-func (s *SpecificConfigService) saveConfigToYAML(config models.SpecificConfig, path string) error {
-	yamlData, err := yaml.Marshal(config)
+// saveConfigToYAML writes a config back to the document it was loaded
+// from, in the form it was loaded in, or to <id>.yaml when it is new. An
+// existing document is updated in place, keeping its comments and formatting.
+// Only resources carry the timestamps.
+func (s *SpecificConfigService) saveConfigToYAML(id string, config models.SpecificConfig) error {
+	src, err := s.sources.get(s.yamlDir, id)
 	if err != nil {
-		return fmt.Errorf("failed to marshal YAML: %w", err)
+		return err
 	}
-
-	if err := os.WriteFile(path, yamlData, 0644); err != nil {
+	var value interface{} = config.WithoutTimestamps()
+	if src.resource {
+		value = models.NewSpecificConfigResource(config)
+	}
+	if err := writeDocument(src, value); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
-
+	s.sources[id] = src
 	return nil
 }
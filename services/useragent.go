@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Device classes a user agent can be classified into.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceTV      = "tv"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// Substrings are checked in order: bots first because crawlers often embed a
+// browser token, tablets before phones because Android tablets and iPads
+// also carry mobile platform names.
+var (
+	botTokens = []string{
+		"bot", "crawler", "spider", "slurp", "facebookexternalhit", "embedly",
+		"headlesschrome", "lighthouse", "curl/", "wget/", "python-requests", "go-http-client",
+	}
+	tvTokens = []string{
+		"smart-tv", "smarttv", "googletv", "appletv", "hbbtv", "crkey", "roku", "tizen tv", "web0s", "afts", "aftb", "aftm",
+	}
+	tabletTokens = []string{
+		"ipad", "tablet", "kindle", "silk/", "playbook", "nexus 7", "nexus 9", "nexus 10", "sm-t",
+	}
+	mobileTokens = []string{
+		"mobi", "iphone", "ipod", "android", "windows phone", "blackberry", "bb10", "opera mini", "iemobile",
+	}
+	desktopTokens = []string{
+		"windows nt", "macintosh", "mac os x", "x11", "linux", "cros",
+	}
+)
+
+// ClassifyUserAgent maps a User-Agent header to a device class.
+func ClassifyUserAgent(userAgent string) string {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return DeviceUnknown
+	}
+
+	switch {
+	case containsAny(ua, botTokens):
+		return DeviceBot
+	case containsAny(ua, tvTokens):
+		return DeviceTV
+	case containsAny(ua, tabletTokens):
+		return DeviceTablet
+	case strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		// Android tablets omit the "Mobile" token phones carry
+		return DeviceTablet
+	case containsAny(ua, mobileTokens):
+		return DeviceMobile
+	case containsAny(ua, desktopTokens):
+		return DeviceDesktop
+	}
+	return DeviceUnknown
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileUserAgentPattern compiles the userAgent of a rule condition. User
+// agents are long free-form strings, so unlike datasource keys a pattern
+// without wildcards matches anywhere in the header and "*" matches any run of
+// characters, "/" included: "iPhone" and "*iPhone*" both match a Safari on
+// iPhone UA. Matching ignores case. "re:x" is a regular expression searched
+// anywhere in the header; anchor it with ^ and $ to match the whole value.
+func compileUserAgentPattern(raw string) (*pattern, error) {
+	expr, isRegex := strings.CutPrefix(raw, regexPrefix)
+	if !isRegex {
+		parts := strings.Split(raw, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		expr = strings.Join(parts, ".*")
+		if len(parts) > 1 {
+			expr = "^" + expr + "$"
+		}
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user agent pattern '%s': %w", raw, err)
+	}
+	return &pattern{raw: raw, kind: patternRegex, re: re}, nil
+}
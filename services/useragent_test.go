@@ -0,0 +1,108 @@
+package services
+
+import (
+	"ssd-assignment-api/models"
+	"testing"
+)
+
+// Real User-Agent headers as sent by browsers, crawlers and devices.
+const (
+	uaIPhoneSafari  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	uaIPad          = "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1"
+	uaAndroidPhone  = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+	uaAndroidTablet = "Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	uaWindowsChrome = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	uaMacFirefox    = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:125.0) Gecko/20100101 Firefox/125.0"
+	uaGooglebot     = "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	uaSmartTV       = "Mozilla/5.0 (SMART-TV; LINUX; Tizen 6.0) AppleWebKit/537.36 (KHTML, like Gecko) 76.0.3809.146/6.0 TV Safari/537.36"
+	uaCurl          = "curl/8.5.0"
+)
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{uaIPhoneSafari, DeviceMobile},
+		{uaAndroidPhone, DeviceMobile},
+		{uaIPad, DeviceTablet},
+		{uaAndroidTablet, DeviceTablet},
+		{uaWindowsChrome, DeviceDesktop},
+		{uaMacFirefox, DeviceDesktop},
+		{uaGooglebot, DeviceBot},
+		{uaCurl, DeviceBot},
+		{uaSmartTV, DeviceTV},
+		{"", DeviceUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyUserAgent(tt.userAgent); got != tt.want {
+			t.Errorf("ClassifyUserAgent(%q) = %s, want %s", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestUserAgentPattern(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		userAgent string
+		want      bool
+	}{
+		{"*iPhone*", uaIPhoneSafari, true},
+		{"iPhone", uaIPhoneSafari, true},
+		{"iphone", uaIPhoneSafari, true},
+		{"*iPhone*", uaAndroidPhone, false},
+		{"Chrome/124", uaWindowsChrome, true},
+		{"*Android*Mobile*", uaAndroidPhone, true},
+		{"*Android*Mobile*", uaAndroidTablet, false},
+		{"Mozilla/5.0 (Windows*", uaWindowsChrome, true},
+		{"Mozilla/5.0 (Windows*", uaMacFirefox, false},
+		{"*Firefox/125.0", uaMacFirefox, true},
+		{"*Firefox/124.0", uaMacFirefox, false},
+		{"Googlebot/2.1", uaGooglebot, true},
+		{"(KHTML, like Gecko)", uaIPad, true},
+		{`re:Chrome/1[0-9]{2}\.`, uaWindowsChrome, true},
+		{`re:Chrome/1[0-9]{2}\.`, uaMacFirefox, false},
+		{`re:^curl/`, uaCurl, true},
+		{`re:^curl/`, uaGooglebot, false},
+		{`re:iphone`, uaIPhoneSafari, false},
+		{`re:(?i)iphone`, uaIPhoneSafari, true},
+	}
+
+	for _, tt := range tests {
+		p, err := compileUserAgentPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compile %q: %v", tt.pattern, err)
+		}
+		if got, _ := p.match(tt.userAgent); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.userAgent, got, tt.want)
+		}
+	}
+
+	if _, err := compileUserAgentPattern("re:("); err == nil {
+		t.Error("expected an invalid regex to fail")
+	}
+}
+
+func TestUserAgentRule(t *testing.T) {
+	s := newTestSpecificService(t, NormalizeOptions{}, models.SpecificConfig{ID: "s", Rules: []models.Rule{
+		{When: models.Condition{UserAgent: "*iPhone*"}, IDs: models.StringSlice{"IOS"}},
+		{When: models.Condition{Device: DeviceTablet}, IDs: models.StringSlice{"TABLET"}},
+	}})
+
+	tests := []struct {
+		userAgent string
+		want      []string
+	}{
+		{uaIPhoneSafari, []string{"IOS"}},
+		{uaIPad, []string{"TABLET"}},
+		{uaWindowsChrome, []string{}},
+	}
+	for _, tt := range tests {
+		req := MatchRequest{Host: "example.com", UserAgent: tt.userAgent, Device: ClassifyUserAgent(tt.userAgent)}
+		got := s.GetMatchingConfigs(req).ConfigIDs
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("user agent %q matched %v, want %v", tt.userAgent, got, tt.want)
+		}
+	}
+}
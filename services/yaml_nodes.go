@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maskWideRunes replaces every rune above U+FFFF in the scalars and comments
+// of a node tree with a private use rune the tree does not contain yet, and
+// returns the replacer that swaps them back.
+func maskWideRunes(root *yaml.Node) *strings.Replacer {
+	used := map[rune]bool{}
+	var nodes []*yaml.Node
+	var walk func(*yaml.Node)
+	walk = func(n *yaml.Node) {
+		nodes = append(nodes, n)
+		for _, text := range []string{n.Value, n.HeadComment, n.LineComment, n.FootComment} {
+			for _, r := range text {
+				used[r] = true
+			}
+		}
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	masks := map[rune]rune{}
+	var pairs []string
+	next := rune(0xE000)
+	mask := func(text string) string {
+		return strings.Map(func(r rune) rune {
+			if r <= 0xFFFF {
+				return r
+			}
+			if masked, ok := masks[r]; ok {
+				return masked
+			}
+			for used[next] && next < 0xF8FF {
+				next++
+			}
+			used[next] = true
+			masks[r] = next
+			pairs = append(pairs, string(next), string(r))
+			return next
+		}, text)
+	}
+
+	for _, n := range nodes {
+		n.Value = mask(n.Value)
+		n.HeadComment = mask(n.HeadComment)
+		n.LineComment = mask(n.LineComment)
+		n.FootComment = mask(n.FootComment)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// mergeNodes returns fresh with as much of current as can be kept: nodes
+// with an unchanged value are reused as they are, and changed nodes keep
+// the comments and, where it still fits, the style of the node they replace.
+func mergeNodes(current, fresh *yaml.Node) *yaml.Node {
+	if current.Kind != fresh.Kind {
+		return withLayout(fresh, current)
+	}
+
+	switch fresh.Kind {
+	case yaml.ScalarNode:
+		if current.Tag == fresh.Tag && current.Value == fresh.Value {
+			return current
+		}
+		merged := withLayout(fresh, current)
+		if current.Tag == fresh.Tag {
+			merged.Style = current.Style
+		}
+		return merged
+
+	case yaml.MappingNode:
+		freshValues := make(map[string]*yaml.Node, len(fresh.Content)/2)
+		for i := 0; i+1 < len(fresh.Content); i += 2 {
+			freshValues[fresh.Content[i].Value] = fresh.Content[i+1]
+		}
+
+		// Keys keep their order; removed keys are dropped and new keys added
+		// at the end in the order of the new document
+		merged := *current
+		merged.Content = nil
+		kept := make(map[string]bool, len(freshValues))
+		for i := 0; i+1 < len(current.Content); i += 2 {
+			key := current.Content[i]
+			value, exists := freshValues[key.Value]
+			if !exists {
+				continue
+			}
+			merged.Content = append(merged.Content, key, mergeNodes(current.Content[i+1], value))
+			kept[key.Value] = true
+		}
+		for i := 0; i+1 < len(fresh.Content); i += 2 {
+			if !kept[fresh.Content[i].Value] {
+				merged.Content = append(merged.Content, fresh.Content[i], fresh.Content[i+1])
+			}
+		}
+		return &merged
+
+	case yaml.SequenceNode:
+		merged := *current
+		merged.Content = mergeSequence(current.Content, fresh.Content)
+		return &merged
+	}
+	return fresh
+}
+
+// mergeSequence aligns the items of two sequences on their longest common
+// subsequence, so an item keeps its comments when items before it are added
+// or removed. Items between two aligned pairs are merged by position.
+func mergeSequence(current, fresh []*yaml.Node) []*yaml.Node {
+	currentKeys := make([]string, len(current))
+	for i, node := range current {
+		currentKeys[i] = nodeKey(node)
+	}
+	freshKeys := make([]string, len(fresh))
+	for i, node := range fresh {
+		freshKeys[i] = nodeKey(node)
+	}
+
+	// lengths[i][j] is the length of the LCS of current[i:] and fresh[j:]
+	lengths := make([][]int, len(current)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(fresh)+1)
+	}
+	for i := len(current) - 1; i >= 0; i-- {
+		for j := len(fresh) - 1; j >= 0; j-- {
+			if currentKeys[i] == freshKeys[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var merged []*yaml.Node
+	var gapCurrent, gapFresh []*yaml.Node
+	flush := func() {
+		for k, node := range gapFresh {
+			if k < len(gapCurrent) {
+				node = mergeNodes(gapCurrent[k], node)
+			}
+			merged = append(merged, node)
+		}
+		gapCurrent, gapFresh = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(current) || j < len(fresh) {
+		switch {
+		case i < len(current) && j < len(fresh) && currentKeys[i] == freshKeys[j]:
+			flush()
+			merged = append(merged, current[i])
+			i, j = i+1, j+1
+		case j == len(fresh) || (i < len(current) && lengths[i+1][j] >= lengths[i][j+1]):
+			gapCurrent = append(gapCurrent, current[i])
+			i++
+		default:
+			gapFresh = append(gapFresh, fresh[j])
+			j++
+		}
+	}
+	flush()
+	return merged
+}
+
+// nodeKey describes the value of a node, ignoring comments and style.
+func nodeKey(node *yaml.Node) string {
+	var b strings.Builder
+	var walk func(*yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			fmt.Fprintf(&b, "%s:%q", n.ShortTag(), n.Value)
+		case yaml.AliasNode:
+			walk(n.Alias)
+		default:
+			fmt.Fprintf(&b, "%d[", n.Kind)
+			for _, child := range n.Content {
+				walk(child)
+				b.WriteString(",")
+			}
+			b.WriteString("]")
+		}
+	}
+	walk(node)
+	return b.String()
+}
+
+// withLayout copies the comments of old onto a copy of node.
+func withLayout(node, old *yaml.Node) *yaml.Node {
+	merged := *node
+	merged.HeadComment = old.HeadComment
+	merged.LineComment = old.LineComment
+	merged.FootComment = old.FootComment
+	return &merged
+}